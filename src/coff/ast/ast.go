@@ -9,6 +9,14 @@ import (
 type HashLiteral struct {
 	Token token.Token
 	Pairs map[Expression]Expression
+	Keys []Expression // keys of Pairs in source order
+	EndToken token.Token // the closing '}'
+}
+
+type MemberExpression struct {
+	Token token.Token // the '.'
+	Object Expression
+	Member *Identifier
 }
 
 type IdxExpression struct {
@@ -17,26 +25,67 @@ type IdxExpression struct {
 	Index Expression
 }
 
+// SliceExpression selects part of a sequence: a[low:high:step]. Bounds
+// that are left out are nil.
+type SliceExpression struct {
+	Token token.Token // the '['
+	Left Expression
+	Low Expression
+	High Expression
+	Step Expression
+	EndToken token.Token // the closing ']'
+}
+
 type ArrLiteral struct {
 	Token token.Token
 	Elements []Expression
+	EndToken token.Token // the closing ']'
+}
+
+// TupleLiteral is a parenthesized, comma-separated list: (a, b), (a,)
+// or ().
+type TupleLiteral struct {
+	Token token.Token // the '('
+	Elements []Expression
+	EndToken token.Token // the closing ')'
+}
+
+// SetLiteral is a set of values: #{a, b}.
+type SetLiteral struct {
+	Token token.Token // the '#'
+	Elements []Expression
+	EndToken token.Token // the closing '}'
 }
 
 type CallExpression struct {
 	Token token.Token
 	Function Expression
 	Arguments []Expression
+	EndToken token.Token // the closing ')'
 }
 
 type FunctionLiteral struct {
 	Token token.Token
-	Parameters []*Identifier
+	Name string // set when the literal is the value of a def statement
+	Parameters []Pattern // parameters with defaults come last
 	Body *BlockStatement
 }
 
+// Required returns how many of params must be passed: those before the
+// first one with a default.
+func Required(params []Pattern) int {
+	for i, p := range params {
+		if _, ok := p.(*DefaultPattern); ok {
+			return i
+		}
+	}
+	return len(params)
+}
+
 type BlockStatement struct {
 	Token token.Token
 	Statements []Statement
+	EndToken token.Token // the closing '}'
 }
 
 type IfExpression struct {
@@ -89,6 +138,14 @@ type Expression interface {
 	expressionNode()
 }
 
+// Pattern is the shape a value is matched against. An Identifier is a
+// pattern that binds the value to its name, or ignores it if the name is
+// "_".
+type Pattern interface {
+	Node
+	patternNode()
+}
+
 type Program struct {
 	Statements []Statement
 }
@@ -105,8 +162,15 @@ func (p *Program) String() string {
 
 func (ds DefStatement) String() string {
 	var out bytes.Buffer
+	if ds.Exported() {
+		out.WriteString(ds.Export.Literal + " ")
+	}
 	out.WriteString(ds.TokenLiteral() + " ")
-	out.WriteString(ds.Name.String())
+	if ds.Pattern != nil {
+		out.WriteString(ds.Pattern.String())
+	} else {
+		out.WriteString(ds.Name.String())
+	}
 	out.WriteString(" = ")
 
 	if ds.Value != nil {
@@ -149,15 +213,112 @@ func (p *Program) TokenLiteral() string {
 	}
 }
 
+// DefStatement binds a name, or the names of a destructuring pattern, to
+// a value.
 type DefStatement struct {
 	Token token.Token
-	Name *Identifier
+	Export token.Token // the export keyword, if the definition is exported
+	Name *Identifier // nil if Pattern is set
+	Pattern Pattern // an array or hash pattern, or nil for a plain name
 	Value Expression
 }
 
 func (ds *DefStatement) statementNode() {}
 func (ds *DefStatement) TokenLiteral() string { return ds.Token.Literal }
 
+// Exported reports whether the definition is exported from its module.
+func (ds *DefStatement) Exported() bool { return ds.Export.Type == token.EXPORT }
+
+// AssignStatement assigns to an existing place, such as a member.
+type AssignStatement struct {
+	Token token.Token // the '='
+	Target Expression
+	Value Expression
+}
+
+func (as *AssignStatement) statementNode() {}
+func (as *AssignStatement) TokenLiteral() string { return as.Token.Literal }
+func (as *AssignStatement) String() string {
+	return as.Target.String() + " = " + as.Value.String() + ";"
+}
+
+// StructStatement declares a struct type with a fixed set of fields.
+type StructStatement struct {
+	Token token.Token // the struct keyword
+	Export token.Token // the export keyword, if the struct is exported
+	Name *Identifier
+	Fields []*Identifier
+	EndToken token.Token // the closing brace
+}
+
+func (ss *StructStatement) statementNode() {}
+func (ss *StructStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *StructStatement) String() string {
+	fields := []string{}
+	for _, f := range ss.Fields {
+		fields = append(fields, f.String())
+	}
+
+	out := "struct " + ss.Name.String() + " {}"
+	if len(fields) > 0 {
+		out = "struct " + ss.Name.String() + " { " + strings.Join(fields, ", ") + " }"
+	}
+	if ss.Exported() {
+		out = "export " + out
+	}
+	return out
+}
+
+// Exported reports whether the struct is exported from its module.
+func (ss *StructStatement) Exported() bool { return ss.Export.Type == token.EXPORT }
+
+// ClassStatement declares a class. Its methods are function literals
+// whose token is the method name.
+type ClassStatement struct {
+	Token token.Token // the class keyword
+	Export token.Token // the export keyword, if the class is exported
+	Name *Identifier
+	Super *Identifier // the class it extends, or nil
+	Methods []*FunctionLiteral
+	EndToken token.Token // the closing brace
+}
+
+func (cs *ClassStatement) statementNode() {}
+func (cs *ClassStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ClassStatement) String() string {
+	var out bytes.Buffer
+
+	if cs.Exported() {
+		out.WriteString("export ")
+	}
+	out.WriteString("class " + cs.Name.String())
+	if cs.Super != nil {
+		out.WriteString(" < " + cs.Super.String())
+	}
+	out.WriteString(" {")
+	for _, m := range cs.Methods {
+		out.WriteString(" " + m.String())
+	}
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// Exported reports whether the class is exported from its module.
+func (cs *ClassStatement) Exported() bool { return cs.Export.Type == token.EXPORT }
+
+type ImportStatement struct {
+	Token token.Token
+	Path *StrLiteral
+	Name *Identifier
+}
+
+func (is *ImportStatement) statementNode() {}
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *ImportStatement) String() string {
+	return is.TokenLiteral() + " \"" + is.Path.Value + "\" as " + is.Name.String() + ";"
+}
+
 type Identifier struct {
 	Token token.Token
 	Value string
@@ -192,6 +353,9 @@ func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
 	out.WriteString(pe.Operator)
+	if pe.Token.Type == token.NOT {
+		out.WriteString(" ")
+	}
 	out.WriteString(pe.Right.String())
 	out.WriteString(")")
 	return out.String()
@@ -299,6 +463,61 @@ func (al *ArrLiteral) String() string {
 	return out.String()
 }
 
+func (tl *TupleLiteral) expressionNode() {}
+func (tl *TupleLiteral) TokenLiteral() string { return tl.Token.Literal }
+func (tl *TupleLiteral) String() string {
+	elements := []string{}
+	for _, el := range tl.Elements {
+		elements = append(elements, el.String())
+	}
+	if len(elements) == 1 {
+		return "(" + elements[0] + ",)"
+	}
+
+	return "(" + strings.Join(elements, ", ") + ")"
+}
+
+func (sl *SetLiteral) expressionNode() {}
+func (sl *SetLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *SetLiteral) String() string {
+	elements := []string{}
+	for _, el := range sl.Elements {
+		elements = append(elements, el.String())
+	}
+
+	return "#{" + strings.Join(elements, ", ") + "}"
+}
+
+func (se *SliceExpression) expressionNode() {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	out.WriteString("[")
+	if se.Low != nil {
+		out.WriteString(se.Low.String())
+	}
+	out.WriteString(":")
+	if se.High != nil {
+		out.WriteString(se.High.String())
+	}
+	if se.Step != nil {
+		out.WriteString(":")
+		out.WriteString(se.Step.String())
+	}
+	out.WriteString("])")
+
+	return out.String()
+}
+
+func (me *MemberExpression) expressionNode() {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) String() string {
+	return "(" + me.Object.String() + "." + me.Member.String() + ")"
+}
+
 func (ie *IdxExpression) expressionNode() {}
 func (ie *IdxExpression) TokenLiteral() string { return ie.Token.Literal }
 func (ie *IdxExpression) String() string {
@@ -319,8 +538,8 @@ func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, key := range hl.Keys {
+		pairs = append(pairs, key.String()+":"+hl.Pairs[key].String())
 	}
 
 	out.WriteString("{")
@@ -328,4 +547,163 @@ func (hl *HashLiteral) String() string {
 	out.WriteString("}")
 	
 	return out.String()
-}
\ No newline at end of file
+}
+// MatchExpression evaluates to the body of the first arm whose pattern
+// matches the subject and whose guard, if any, holds.
+type MatchExpression struct {
+	Token token.Token // the match keyword
+	Subject Expression
+	Arms []*MatchArm
+	EndToken token.Token // the closing '}'
+}
+
+type MatchArm struct {
+	Pattern Pattern
+	Guard Expression // nil if the arm has no guard
+	Body Expression
+}
+
+func (me *MatchExpression) expressionNode() {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	return "match " + me.Subject.String() + " { " + strings.Join(arms, ", ") + " }"
+}
+
+func (ma *MatchArm) String() string {
+	out := ma.Pattern.String()
+	if ma.Guard != nil {
+		out += " if " + ma.Guard.String()
+	}
+
+	return out + " => " + ma.Body.String()
+}
+
+// LiteralPattern matches values equal to a literal, which may be negated.
+type LiteralPattern struct {
+	Value Expression
+}
+
+func (lp *LiteralPattern) patternNode() {}
+func (lp *LiteralPattern) TokenLiteral() string { return lp.Value.TokenLiteral() }
+func (lp *LiteralPattern) String() string { return lp.Value.String() }
+
+// ArrPattern matches arrays and tuples element by element. Without a
+// rest pattern the lengths must be equal; with one, the remaining
+// elements are bound to it as an array or tuple.
+type ArrPattern struct {
+	Token token.Token // the '['
+	Elements []Pattern
+	Rest *Identifier // nil if there is no rest pattern
+	EndToken token.Token // the ']'
+}
+
+func (ap *ArrPattern) patternNode() {}
+func (ap *ArrPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrPattern) String() string {
+	elements := []string{}
+	for _, el := range ap.Elements {
+		elements = append(elements, el.String())
+	}
+	if ap.Rest != nil {
+		elements = append(elements, "..." + ap.Rest.String())
+	}
+
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// HashPattern matches hashes that have each of its keys with a value
+// matching the key's pattern. Other keys are ignored. A shorthand key, as
+// in {name}, is a StrLiteral whose token is the identifier, with the
+// identifier as its pattern.
+type HashPattern struct {
+	Token token.Token // the '{'
+	Keys []Expression // literals, in source order
+	Values map[Expression]Pattern
+	EndToken token.Token // the '}'
+}
+
+func (hp *HashPattern) patternNode() {}
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+func (hp *HashPattern) String() string {
+	pairs := []string{}
+	for _, key := range hp.Keys {
+		if IsShorthand(key) {
+			pairs = append(pairs, hp.Values[key].String())
+			continue
+		}
+		pairs = append(pairs, key.String() + ": " + hp.Values[key].String())
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+func (i *Identifier) patternNode() {}
+
+// IsShorthand reports whether key of a hash pattern was written as a bare
+// name, as in {name}.
+func IsShorthand(key Expression) bool {
+	str, ok := key.(*StrLiteral)
+	return ok && str.Token.Type == token.ID
+}
+
+// DefaultPattern is a pattern with a value to use when there is nothing
+// to match, such as a missing argument, array element or hash key.
+type DefaultPattern struct {
+	Token token.Token // the '='
+	Pattern Pattern
+	Default Expression
+}
+
+func (dp *DefaultPattern) patternNode() {}
+func (dp *DefaultPattern) TokenLiteral() string { return dp.Token.Literal }
+func (dp *DefaultPattern) String() string {
+	return dp.Pattern.String() + " = " + dp.Default.String()
+}
+
+// ThrowStatement raises its value as an error.
+type ThrowStatement struct {
+	Token token.Token // the throw keyword
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode() {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) String() string { return "throw " + ts.Value.String() + ";" }
+
+// TryExpression evaluates to its body or, if the body fails, to its
+// catch block. The finally block runs either way.
+type TryExpression struct {
+	Token token.Token // the try keyword
+	Body *BlockStatement
+	Param *Identifier // the name the caught error is bound to, if any
+	Catch *BlockStatement // nil if there is no catch block
+	Finally *BlockStatement // nil if there is no finally block
+}
+
+func (te *TryExpression) expressionNode() {}
+func (te *TryExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *TryExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("try ")
+	out.WriteString(te.Body.String())
+
+	if te.Catch != nil {
+		out.WriteString(" catch")
+		if te.Param != nil {
+			out.WriteString("(" + te.Param.String() + ")")
+		}
+		out.WriteString(" ")
+		out.WriteString(te.Catch.String())
+	}
+	if te.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(te.Finally.String())
+	}
+
+	return out.String()
+}
@@ -11,12 +11,12 @@ func TestString(t *testing.T) {
 			&DefStatement{
 				Token: token.Token{Type: token.DEF, Literal: "def"},
 				Name: &Identifier{
-					Token: token.Token{Type: token.ID, Literal: "thisVar"}
-					Value: "thisVar"
+					Token: token.Token{Type: token.ID, Literal: "thisVar"},
+					Value: "thisVar",
 				},
 				Value: &Identifier{
-					Token: token.Token{Type: token.ID, Literal: "thatVar"}
-					Value: "thatVar"
+					Token: token.Token{Type: token.ID, Literal: "thatVar"},
+					Value: "thatVar",
 				},
 			},
 		},
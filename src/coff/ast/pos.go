@@ -0,0 +1,160 @@
+package ast
+
+import "coff-src/src/coff/token"
+
+// TokenOf returns the token node was created from. For operators and
+// calls that is the operator or the opening parenthesis, not the first
+// token of the source text; see Start.
+func TokenOf(node Node) token.Token {
+	switch n := node.(type) {
+	case *DefStatement:
+		return n.Token
+	case *AssignStatement:
+		return n.Token
+	case *StructStatement:
+		return n.Token
+	case *ClassStatement:
+		return n.Token
+	case *ImportStatement:
+		return n.Token
+	case *RetStatement:
+		return n.Token
+	case *ThrowStatement:
+		return n.Token
+	case *ExpressionStatement:
+		return n.Token
+	case *BlockStatement:
+		return n.Token
+	case *Identifier:
+		return n.Token
+	case *IntLiteral:
+		return n.Token
+	case *StrLiteral:
+		return n.Token
+	case *Boolean:
+		return n.Token
+	case *PrefixExpression:
+		return n.Token
+	case *InfixExpression:
+		return n.Token
+	case *IfExpression:
+		return n.Token
+	case *FunctionLiteral:
+		return n.Token
+	case *CallExpression:
+		return n.Token
+	case *ArrLiteral:
+		return n.Token
+	case *TupleLiteral:
+		return n.Token
+	case *SetLiteral:
+		return n.Token
+	case *IdxExpression:
+		return n.Token
+	case *SliceExpression:
+		return n.Token
+	case *MemberExpression:
+		return n.Token
+	case *HashLiteral:
+		return n.Token
+	case *MatchExpression:
+		return n.Token
+	case *TryExpression:
+		return n.Token
+	case *LiteralPattern:
+		return TokenOf(n.Value)
+	case *DefaultPattern:
+		return n.Token
+	case *ArrPattern:
+		return n.Token
+	case *HashPattern:
+		return n.Token
+	}
+
+	return token.Token{}
+}
+
+// Start returns the first token of the source text of node.
+func Start(node Node) token.Token {
+	switch n := node.(type) {
+	case *Program:
+		if len(n.Statements) > 0 {
+			return Start(n.Statements[0])
+		}
+	case *InfixExpression:
+		return Start(n.Left)
+	case *CallExpression:
+		return Start(n.Function)
+	case *IdxExpression:
+		return Start(n.Left)
+	case *SliceExpression:
+		return Start(n.Left)
+	case *MemberExpression:
+		return Start(n.Object)
+	case *LiteralPattern:
+		return Start(n.Value)
+	case *DefaultPattern:
+		return Start(n.Pattern)
+	case *AssignStatement:
+		return Start(n.Target)
+	case *DefStatement:
+		if n.Exported() {
+			return n.Export
+		}
+	case *StructStatement:
+		if n.Exported() {
+			return n.Export
+		}
+	case *ClassStatement:
+		if n.Exported() {
+			return n.Export
+		}
+	}
+
+	return TokenOf(node)
+}
+
+// End returns the last token of the source text of node that is known
+// to the tree: a closing bracket where the node records one, otherwise
+// the token of the innermost node that appears last.
+func End(node Node) token.Token {
+	var end token.Token
+	later := func(t token.Token) {
+		if t.Line > end.Line || t.Line == end.Line && t.Column > end.Column {
+			end = t
+		}
+	}
+
+	Inspect(node, func(n Node) bool {
+		later(TokenOf(n))
+		switch n := n.(type) {
+		case *BlockStatement:
+			later(n.EndToken)
+		case *ArrLiteral:
+			later(n.EndToken)
+		case *TupleLiteral:
+			later(n.EndToken)
+		case *SetLiteral:
+			later(n.EndToken)
+		case *SliceExpression:
+			later(n.EndToken)
+		case *HashLiteral:
+			later(n.EndToken)
+		case *CallExpression:
+			later(n.EndToken)
+		case *StructStatement:
+			later(n.EndToken)
+		case *ClassStatement:
+			later(n.EndToken)
+		case *MatchExpression:
+			later(n.EndToken)
+		case *ArrPattern:
+			later(n.EndToken)
+		case *HashPattern:
+			later(n.EndToken)
+		}
+		return true
+	})
+
+	return end
+}
@@ -0,0 +1,184 @@
+package ast
+
+import "reflect"
+
+// Inspect traverses the tree rooted at node in depth-first order. It
+// calls f for each node; if f returns false, the children of that node
+// are skipped.
+func Inspect(node Node, f func(Node) bool) {
+	if isNil(node) || !f(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		for _, s := range n.Statements {
+			Inspect(s, f)
+		}
+	case *DefStatement:
+		Inspect(n.Name, f)
+		Inspect(n.Pattern, f)
+		Inspect(n.Value, f)
+	case *AssignStatement:
+		Inspect(n.Target, f)
+		Inspect(n.Value, f)
+	case *StructStatement:
+		Inspect(n.Name, f)
+		for _, field := range n.Fields {
+			Inspect(field, f)
+		}
+	case *ClassStatement:
+		Inspect(n.Name, f)
+		Inspect(n.Super, f)
+		for _, m := range n.Methods {
+			Inspect(m, f)
+		}
+	case *ImportStatement:
+		Inspect(n.Path, f)
+		Inspect(n.Name, f)
+	case *RetStatement:
+		Inspect(n.RetVal, f)
+	case *ThrowStatement:
+		Inspect(n.Value, f)
+	case *ExpressionStatement:
+		Inspect(n.Expression, f)
+	case *BlockStatement:
+		for _, s := range n.Statements {
+			Inspect(s, f)
+		}
+	case *PrefixExpression:
+		Inspect(n.Right, f)
+	case *InfixExpression:
+		Inspect(n.Left, f)
+		Inspect(n.Right, f)
+	case *IfExpression:
+		Inspect(n.Condition, f)
+		Inspect(n.Consequence, f)
+		Inspect(n.Alternative, f)
+	case *FunctionLiteral:
+		for _, p := range n.Parameters {
+			Inspect(p, f)
+		}
+		Inspect(n.Body, f)
+	case *CallExpression:
+		Inspect(n.Function, f)
+		for _, a := range n.Arguments {
+			Inspect(a, f)
+		}
+	case *ArrLiteral:
+		for _, e := range n.Elements {
+			Inspect(e, f)
+		}
+	case *TupleLiteral:
+		for _, e := range n.Elements {
+			Inspect(e, f)
+		}
+	case *SetLiteral:
+		for _, e := range n.Elements {
+			Inspect(e, f)
+		}
+	case *IdxExpression:
+		Inspect(n.Left, f)
+		Inspect(n.Index, f)
+	case *SliceExpression:
+		Inspect(n.Left, f)
+		for _, e := range []Expression{n.Low, n.High, n.Step} {
+			if e != nil {
+				Inspect(e, f)
+			}
+		}
+	case *MemberExpression:
+		Inspect(n.Object, f)
+		Inspect(n.Member, f)
+	case *MatchExpression:
+		Inspect(n.Subject, f)
+		for _, arm := range n.Arms {
+			Inspect(arm.Pattern, f)
+			Inspect(arm.Guard, f)
+			Inspect(arm.Body, f)
+		}
+	case *TryExpression:
+		Inspect(n.Body, f)
+		Inspect(n.Param, f)
+		Inspect(n.Catch, f)
+		Inspect(n.Finally, f)
+	case *LiteralPattern:
+		Inspect(n.Value, f)
+	case *DefaultPattern:
+		Inspect(n.Pattern, f)
+		Inspect(n.Default, f)
+	case *ArrPattern:
+		for _, el := range n.Elements {
+			Inspect(el, f)
+		}
+		Inspect(n.Rest, f)
+	case *HashPattern:
+		for _, k := range n.Keys {
+			Inspect(k, f)
+			Inspect(n.Values[k], f)
+		}
+	case *HashLiteral:
+		for _, k := range n.Keys {
+			Inspect(k, f)
+			Inspect(n.Pairs[k], f)
+		}
+	}
+}
+
+// Bindings returns the identifiers pattern binds, in source order. The
+// wildcard "_" binds nothing and is left out.
+func Bindings(pattern Pattern) []*Identifier {
+	ids := []*Identifier{}
+	switch p := pattern.(type) {
+	case *Identifier:
+		if p.Value != "_" {
+			ids = append(ids, p)
+		}
+	case *DefaultPattern:
+		ids = append(ids, Bindings(p.Pattern)...)
+	case *ArrPattern:
+		for _, el := range p.Elements {
+			ids = append(ids, Bindings(el)...)
+		}
+		if p.Rest != nil {
+			ids = append(ids, Bindings(p.Rest)...)
+		}
+	case *HashPattern:
+		for _, k := range p.Keys {
+			ids = append(ids, Bindings(p.Values[k])...)
+		}
+	}
+
+	return ids
+}
+
+// Defaults returns the default values in pattern, in source order.
+func Defaults(pattern Pattern) []Expression {
+	defaults := []Expression{}
+	switch p := pattern.(type) {
+	case *DefaultPattern:
+		defaults = append(defaults, Defaults(p.Pattern)...)
+		defaults = append(defaults, p.Default)
+	case *ArrPattern:
+		for _, el := range p.Elements {
+			defaults = append(defaults, Defaults(el)...)
+		}
+	case *HashPattern:
+		for _, k := range p.Keys {
+			defaults = append(defaults, Defaults(p.Values[k])...)
+		}
+	}
+
+	return defaults
+}
+
+// isNil reports whether node is nil or a typed nil pointer, as left
+// behind by the parser for missing optional parts such as an else block.
+func isNil(node Node) bool {
+	if node == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(node)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"coff-src/src/coff/dap"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+)
+
+// runDap implements `coff dap [-listen addr]`, a debug adapter on
+// standard input and output, or for one client connecting to addr.
+func runDap(args []string) int {
+	flags := flag.NewFlagSet("dap", flag.ContinueOnError)
+	listen := flags.String("listen", "", "accept one client on this TCP address instead of using stdio")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *listen == "" {
+		if err := dap.Serve(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "coff dap: %s\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	l, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "coff dap: %s\n", err)
+		return 1
+	}
+	defer l.Close()
+	fmt.Fprintf(os.Stderr, "coff dap: listening on %s\n", l.Addr())
+
+	conn, err := l.Accept()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "coff dap: %s\n", err)
+		return 1
+	}
+	defer conn.Close()
+
+	if err := dap.Serve(conn, conn); err != nil {
+		fmt.Fprintf(os.Stderr, "coff dap: %s\n", err)
+		return 1
+	}
+	return 0
+}
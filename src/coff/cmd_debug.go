@@ -0,0 +1,27 @@
+package main
+
+import (
+	"coff-src/src/coff/debug"
+	"fmt"
+	"os"
+)
+
+// runDebug implements `coff debug file`.
+func runDebug(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: coff debug file")
+		return 2
+	}
+
+	src, err := os.ReadFile(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "coff debug: %s\n", err)
+		return 1
+	}
+
+	if err := debug.RunTerminal(args[0], string(src), os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", args[0], err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"coff-src/src/coff/format"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// runFmt implements `coff fmt [-w] [files...]`. Without files it formats
+// standard input.
+func runFmt(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := flags.Bool("w", false, "write result to the source file instead of standard output")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "coff fmt: %s\n", err)
+			return 1
+		}
+		out, err := format.Source(src)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "<stdin>: %s\n", err)
+			return 1
+		}
+		os.Stdout.Write(out)
+		return 0
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		if err := formatFile(path, *write); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+			status = 1
+		}
+	}
+
+	return status
+}
+
+func formatFile(path string, write bool) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	out, err := format.Source(src)
+	if err != nil {
+		return err
+	}
+
+	if !write {
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+
+	if bytes.Equal(src, out) {
+		return nil
+	}
+	return os.WriteFile(path, out, 0644)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"coff-src/src/coff/lint"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runLint implements `coff lint files...`. It exits with status 1 if any
+// file has issues.
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "coff lint: %s\n", err)
+			status = 1
+			continue
+		}
+
+		for _, issue := range lint.Source(src) {
+			fmt.Printf("%s:%s\n", path, issue)
+			status = 1
+		}
+	}
+
+	return status
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"coff-src/src/coff/lsp"
+	"fmt"
+	"os"
+)
+
+// runLsp implements `coff lsp`, a language server on standard input and
+// output.
+func runLsp(args []string) int {
+	if err := lsp.Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "coff lsp: %s\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"coff-src/src/coff/lexer"
+	"coff-src/src/coff/object"
+	"coff-src/src/coff/parser"
+	"coff-src/src/coff/profile"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runProfile implements `coff profile [-pprof file] [-folded file] file`.
+// The program's output goes to standard output and the report to
+// standard error.
+func runProfile(args []string) int {
+	flags := flag.NewFlagSet("profile", flag.ContinueOnError)
+	pprofPath := flags.String("pprof", "", "write a pprof profile to this file")
+	foldedPath := flags.String("folded", "", "write folded stacks for flame graphs to this file")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: coff profile [-pprof file] [-folded file] file")
+		return 2
+	}
+	path := flags.Arg(0)
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "coff profile: %s\n", err)
+		return 1
+	}
+
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, strings.Join(p.Errors(), "\n"))
+		return 1
+	}
+
+	profiler := profile.New(path)
+	status := 0
+	env := object.NewEnv()
+	env.SetPath(path)
+	result := profiler.Run(program, env)
+	if result != nil && result.Type() == object.ERR_OBJ {
+		fmt.Fprintln(os.Stderr, result.Inspect())
+		status = 1
+	}
+
+	profiler.WriteReport(os.Stderr)
+
+	write := func(path string, write func(f *os.File) error) {
+		if path == "" {
+			return
+		}
+		f, err := os.Create(path)
+		if err == nil {
+			err = write(f)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "coff profile: %s\n", err)
+			status = 1
+		}
+	}
+	write(*pprofPath, func(f *os.File) error { return profiler.WritePprof(f) })
+	write(*foldedPath, func(f *os.File) error { return profiler.WriteFolded(f) })
+
+	return status
+}
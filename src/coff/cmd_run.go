@@ -0,0 +1,75 @@
+package main
+
+import (
+	"coff-src/src/coff/eval"
+	"coff-src/src/coff/lexer"
+	"coff-src/src/coff/object"
+	"coff-src/src/coff/parser"
+	"coff-src/src/coff/trace"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// runRun implements `coff run [-trace] [-trace-func names] [-trace-out
+// file] file`.
+func runRun(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	traceFlag := flags.Bool("trace", false, "log each call and return of a CoffLang function; builtins are not traced")
+	traceFuncs := flags.String("trace-func", "", "comma-separated names of the functions to trace (implies -trace)")
+	traceOut := flags.String("trace-out", "", "write the trace to this file instead of standard error (implies -trace)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: coff run [-trace] [-trace-func names] [-trace-out file] file")
+		return 2
+	}
+	path := flags.Arg(0)
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "coff run: %s\n", err)
+		return 1
+	}
+
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, strings.Join(p.Errors(), "\n"))
+		return 1
+	}
+
+	if *traceFlag || *traceFuncs != "" || *traceOut != "" {
+		out := io.Writer(os.Stderr)
+		if *traceOut != "" {
+			f, err := os.Create(*traceOut)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "coff run: %s\n", err)
+				return 1
+			}
+			defer f.Close()
+			out = f
+		}
+
+		functions := []string{}
+		if *traceFuncs != "" {
+			functions = strings.Split(*traceFuncs, ",")
+		}
+		defer eval.AddHooks(trace.New(out, functions...).Hooks())()
+	}
+
+	env := object.NewEnv()
+	env.SetPath(path)
+	result := eval.Eval(program, env)
+	if err, ok := result.(*object.Error); ok {
+		fmt.Fprintln(os.Stderr, err.Inspect())
+		for _, entry := range err.Stack {
+			fmt.Fprintln(os.Stderr, "\tat " + entry)
+		}
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"coff-src/src/coff/cover"
+	"coff-src/src/coff/eval"
+	"coff-src/src/coff/tester"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// runTest implements `coff test [flags] [paths...]`. It runs the tests
+// in the *_test.coff files found under paths, the current directory by
+// default, and exits with status 1 if any fails.
+func runTest(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	format := flags.String("format", "text", "output format: text, json or tap")
+	verbose := flags.Bool("v", false, "list passing tests too")
+	coverFlag := flags.Bool("cover", false, "report statement and branch coverage")
+	coverProfile := flags.String("coverprofile", "", "write coverage in LCOV format to this file (implies -cover)")
+	coverHTML := flags.String("coverhtml", "", "write coverage as annotated HTML to this file (implies -cover)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "text" && *format != "json" && *format != "tap" {
+		fmt.Fprintf(os.Stderr, "coff test: unknown format %q\n", *format)
+		return 2
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	files, err := tester.Files(paths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "coff test: %s\n", err)
+		return 1
+	}
+
+	runner := &tester.Runner{}
+	if *coverFlag || *coverProfile != "" || *coverHTML != "" {
+		runner.Coverage = cover.New()
+		defer eval.AddHooks(runner.Coverage.Hooks())()
+	}
+
+	results := []tester.Result{}
+	for _, file := range files {
+		results = append(results, runner.RunFile(file)...)
+	}
+
+	status := 0
+	if tester.Failed(results) != 0 {
+		status = 1
+	}
+
+	// Keep machine-readable output alone on standard output.
+	summary := io.Writer(os.Stdout)
+	switch *format {
+	case "text":
+		tester.WriteText(os.Stdout, files, results, *verbose)
+	case "json":
+		tester.WriteJSON(os.Stdout, results)
+		summary = os.Stderr
+	case "tap":
+		tester.WriteTAP(os.Stdout, results)
+		summary = os.Stderr
+	}
+
+	if runner.Coverage == nil {
+		return status
+	}
+	runner.Coverage.WriteSummary(summary)
+
+	write := func(path string, write func(w io.Writer) error) {
+		if path == "" {
+			return
+		}
+		f, err := os.Create(path)
+		if err == nil {
+			err = write(f)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "coff test: %s\n", err)
+			status = 1
+		}
+	}
+	write(*coverProfile, runner.Coverage.WriteLCOV)
+	write(*coverHTML, runner.Coverage.WriteHTML)
+
+	return status
+}
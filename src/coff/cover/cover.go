@@ -0,0 +1,213 @@
+// Package cover records which statements of a CoffLang program run and
+// which way each if expression goes, and reports the coverage as a
+// summary, in LCOV format or as annotated HTML source.
+package cover
+
+import (
+	"coff-src/src/coff/ast"
+	"coff-src/src/coff/eval"
+	"coff-src/src/coff/object"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Statement is a statement and the number of times it ran. Blocks are
+// not statements of their own; their statements are.
+type Statement struct {
+	Node  ast.Statement
+	Line  int
+	Count int
+}
+
+// Branch is an if expression and the number of times each of its arms
+// was taken. An if without an else still has an arm for a false
+// condition.
+type Branch struct {
+	Node     *ast.IfExpression
+	Line     int
+	Taken    int // times the condition was true
+	NotTaken int // times it was false
+}
+
+// File is the coverage of one source file.
+type File struct {
+	Path       string
+	Source     string
+	Statements []*Statement
+	Branches   []*Branch
+}
+
+type Coverage struct {
+	Files []*File
+
+	statements map[ast.Node]*Statement
+	branches   map[*ast.IfExpression]*Branch
+}
+
+func New() *Coverage {
+	return &Coverage{
+		statements: map[ast.Node]*Statement{},
+		branches:   map[*ast.IfExpression]*Branch{},
+	}
+}
+
+// Add registers program, parsed from src at path, so that its statements
+// are counted while it runs. Adding a program again returns the existing
+// file.
+func (c *Coverage) Add(path string, src string, program *ast.Program) *File {
+	for _, f := range c.Files {
+		if f.Path == path {
+			return f
+		}
+	}
+
+	f := &File{Path: path, Source: src}
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.BlockStatement:
+		case ast.Statement:
+			s := &Statement{Node: node, Line: ast.Start(node).Line}
+			f.Statements = append(f.Statements, s)
+			c.statements[node] = s
+		case *ast.IfExpression:
+			b := &Branch{Node: node, Line: node.Token.Line}
+			f.Branches = append(f.Branches, b)
+			c.branches[node] = b
+		}
+		return true
+	})
+	c.Files = append(c.Files, f)
+
+	return f
+}
+
+// Hooks returns the evaluation hooks that record coverage, to be
+// installed with eval.AddHooks while the added programs run.
+func (c *Coverage) Hooks() *eval.Hooks {
+	return &eval.Hooks{
+		Node: func(node ast.Node, env *object.Env) {
+			if s, ok := c.statements[node]; ok {
+				s.Count++
+			}
+		},
+		Branch: func(node *ast.IfExpression, taken bool) {
+			b, ok := c.branches[node]
+			if !ok {
+				return
+			}
+			if taken {
+				b.Taken++
+			} else {
+				b.NotTaken++
+			}
+		},
+	}
+}
+
+// StatementsRun returns the number of statements that ran at least once.
+func (f *File) StatementsRun() int {
+	n := 0
+	for _, s := range f.Statements {
+		if s.Count > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// ArmsTaken returns the number of branch arms taken at least once. Each
+// branch has two arms.
+func (f *File) ArmsTaken() int {
+	n := 0
+	for _, b := range f.Branches {
+		if b.Taken > 0 {
+			n++
+		}
+		if b.NotTaken > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// StatementPercent returns the percentage of statements run, 100 if
+// there are none.
+func (f *File) StatementPercent() float64 {
+	return percent(f.StatementsRun(), len(f.Statements))
+}
+
+// BranchPercent returns the percentage of branch arms taken, 100 if
+// there are none.
+func (f *File) BranchPercent() float64 {
+	return percent(f.ArmsTaken(), 2*len(f.Branches))
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return 100 * float64(n) / float64(total)
+}
+
+// WriteSummary writes a line with the coverage percentages of each file.
+func (c *Coverage) WriteSummary(w io.Writer) error {
+	for _, f := range c.Files {
+		_, err := fmt.Fprintf(w, "%s: %.1f%% of statements, %.1f%% of branches\n",
+			f.Path, f.StatementPercent(), f.BranchPercent())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lineCounts returns the execution count of each line starting a
+// statement: the highest count of those statements.
+func (f *File) lineCounts() map[int]int {
+	counts := map[int]int{}
+	for _, s := range f.Statements {
+		if count, ok := counts[s.Line]; !ok || s.Count > count {
+			counts[s.Line] = s.Count
+		}
+	}
+	return counts
+}
+
+// WriteLCOV writes the coverage in the LCOV tracefile format.
+func (c *Coverage) WriteLCOV(w io.Writer) error {
+	for _, f := range c.Files {
+		fmt.Fprintf(w, "TN:\nSF:%s\n", f.Path)
+
+		for i, b := range f.Branches {
+			for arm, count := range []int{b.Taken, b.NotTaken} {
+				taken := "-"
+				if b.Taken+b.NotTaken > 0 {
+					taken = fmt.Sprint(count)
+				}
+				fmt.Fprintf(w, "BRDA:%d,%d,%d,%s\n", b.Line, i, arm, taken)
+			}
+		}
+		fmt.Fprintf(w, "BRF:%d\nBRH:%d\n", 2*len(f.Branches), f.ArmsTaken())
+
+		counts := f.lineCounts()
+		lines := []int{}
+		for line := range counts {
+			lines = append(lines, line)
+		}
+		sort.Ints(lines)
+
+		hit := 0
+		for _, line := range lines {
+			fmt.Fprintf(w, "DA:%d,%d\n", line, counts[line])
+			if counts[line] > 0 {
+				hit++
+			}
+		}
+
+		if _, err := fmt.Fprintf(w, "LF:%d\nLH:%d\nend_of_record\n", len(lines), hit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
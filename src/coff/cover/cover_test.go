@@ -0,0 +1,122 @@
+package cover
+
+import (
+	"bytes"
+	"coff-src/src/coff/eval"
+	"coff-src/src/coff/lexer"
+	"coff-src/src/coff/object"
+	"coff-src/src/coff/parser"
+	"strings"
+	"testing"
+)
+
+const input = `def abs = fun(x) {
+	if (x < 0) {
+		ret -x;
+	}
+	ret x;
+};
+def unused = fun() { ret 1; };
+abs(5);
+`
+
+func run(t *testing.T) *Coverage {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	c := New()
+	c.Add("abs.coff", input, program)
+	remove := eval.AddHooks(c.Hooks())
+	defer remove()
+	eval.Eval(program, object.NewEnv())
+
+	return c
+}
+
+func TestCounts(t *testing.T) {
+	f := run(t).Files[0]
+
+	expected := map[int]int{1: 1, 2: 1, 3: 0, 5: 1, 7: 1, 8: 1}
+	if len(f.Statements) != 7 {
+		t.Fatalf("expected 7 statements, got=%d", len(f.Statements))
+	}
+	for _, s := range f.Statements {
+		if s.Line == 7 && s.Node.TokenLiteral() == "ret" {
+			if s.Count != 0 {
+				t.Errorf("body of unused ran %d times", s.Count)
+			}
+			continue
+		}
+		if s.Count != expected[s.Line] {
+			t.Errorf("statement on line %d ran %d times, expected %d", s.Line, s.Count, expected[s.Line])
+		}
+	}
+
+	if len(f.Branches) != 1 || f.Branches[0].Taken != 0 || f.Branches[0].NotTaken != 1 {
+		t.Errorf("wrong branches: %+v", f.Branches)
+	}
+
+	if f.StatementsRun() != 5 || f.ArmsTaken() != 1 {
+		t.Errorf("wrong totals: %d statements run, %d arms taken", f.StatementsRun(), f.ArmsTaken())
+	}
+}
+
+func TestSummary(t *testing.T) {
+	var out bytes.Buffer
+	run(t).WriteSummary(&out)
+
+	expected := "abs.coff: 71.4% of statements, 50.0% of branches\n"
+	if out.String() != expected {
+		t.Errorf("wrong summary. expected=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestLCOV(t *testing.T) {
+	var out bytes.Buffer
+	if err := run(t).WriteLCOV(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `TN:
+SF:abs.coff
+BRDA:2,0,0,0
+BRDA:2,0,1,1
+BRF:2
+BRH:1
+DA:1,1
+DA:2,1
+DA:3,0
+DA:5,1
+DA:7,1
+DA:8,1
+LF:6
+LH:5
+end_of_record
+`
+	if out.String() != expected {
+		t.Errorf("wrong LCOV.\nexpected=%q\ngot=     %q", expected, out.String())
+	}
+}
+
+func TestHTML(t *testing.T) {
+	var out bytes.Buffer
+	if err := run(t).WriteHTML(&out); err != nil {
+		t.Fatal(err)
+	}
+	html := out.String()
+
+	for _, expected := range []string{
+		`<tr class="partial"><td class="number">2</td><td class="count">1</td><td class="source"><pre>	if (x &lt; 0) {</pre></td></tr>`,
+		`<tr class="uncovered"><td class="number">3</td>`,
+		`<tr class=""><td class="number">4</td><td class="count"></td>`,
+		`<tr class="partial"><td class="number">7</td>`,
+		`<tr class="covered"><td class="number">8</td>`,
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("HTML does not contain %q", expected)
+		}
+	}
+}
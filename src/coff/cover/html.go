@@ -0,0 +1,110 @@
+package cover
+
+import (
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Classes of source lines in the HTML report.
+const (
+	COVERED   = "covered"
+	UNCOVERED = "uncovered"
+	PARTIAL   = "partial" // some statements or branch arms on the line did not run
+)
+
+type htmlLine struct {
+	Number int
+	Text   string
+	Class  string
+	Count  string
+}
+
+type htmlFile struct {
+	Path       string
+	Statements float64
+	Branches   float64
+	Lines      []htmlLine
+}
+
+var htmlTemplate = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>CoffLang coverage</title>
+<style>
+body { font-family: sans-serif; }
+pre { font-family: monospace; margin: 0; }
+table { border-collapse: collapse; }
+td { padding: 0 0.5em; vertical-align: top; }
+td.number, td.count { color: #888; text-align: right; }
+tr.covered td.source { background: #dfd; }
+tr.uncovered td.source { background: #fdd; }
+tr.partial td.source { background: #ffc; }
+</style>
+</head>
+<body>
+{{- range .}}
+<h2>{{.Path}}</h2>
+<p>{{printf "%.1f" .Statements}}% of statements, {{printf "%.1f" .Branches}}% of branches</p>
+<table>
+{{- range .Lines}}
+<tr class="{{.Class}}"><td class="number">{{.Number}}</td><td class="count">{{.Count}}</td><td class="source"><pre>{{.Text}}</pre></td></tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes the sources of the files as an HTML page, with each
+// line marked by whether its statements ran.
+func (c *Coverage) WriteHTML(w io.Writer) error {
+	files := []htmlFile{}
+	for _, f := range c.Files {
+		files = append(files, htmlFile{
+			Path:       f.Path,
+			Statements: f.StatementPercent(),
+			Branches:   f.BranchPercent(),
+			Lines:      f.htmlLines(),
+		})
+	}
+	return htmlTemplate.Execute(w, files)
+}
+
+func (f *File) htmlLines() []htmlLine {
+	run := map[int]int{}
+	missed := map[int]int{}
+	for _, s := range f.Statements {
+		if s.Count > 0 {
+			run[s.Line]++
+		} else {
+			missed[s.Line]++
+		}
+	}
+	for _, b := range f.Branches {
+		if b.Taken+b.NotTaken > 0 && (b.Taken == 0 || b.NotTaken == 0) {
+			missed[b.Line]++
+		}
+	}
+	counts := f.lineCounts()
+
+	lines := []htmlLine{}
+	for i, text := range strings.Split(strings.TrimSuffix(f.Source, "\n"), "\n") {
+		line := htmlLine{Number: i + 1, Text: text}
+		switch {
+		case run[line.Number] > 0 && missed[line.Number] > 0:
+			line.Class = PARTIAL
+		case run[line.Number] > 0:
+			line.Class = COVERED
+		case missed[line.Number] > 0:
+			line.Class = UNCOVERED
+		}
+		if count, ok := counts[line.Number]; ok {
+			line.Count = strconv.Itoa(count)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
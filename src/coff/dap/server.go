@@ -0,0 +1,599 @@
+// Package dap implements the Debug Adapter Protocol on top of package
+// debug, so that editors can debug CoffLang programs. A session debugs
+// one program, which runs on its own goroutine while the session keeps
+// answering requests.
+package dap
+
+import (
+	"bufio"
+	"coff-src/src/coff/ast"
+	"coff-src/src/coff/debug"
+	"coff-src/src/coff/eval"
+	"coff-src/src/coff/lexer"
+	"coff-src/src/coff/object"
+	"coff-src/src/coff/parser"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const threadID = 1
+
+type request struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+type response struct {
+	Seq        int         `json:"seq"`
+	Type       string      `json:"type"`
+	RequestSeq int         `json:"request_seq"`
+	Command    string      `json:"command"`
+	Success    bool        `json:"success"`
+	Message    string      `json:"message,omitempty"`
+	Body       interface{} `json:"body,omitempty"`
+}
+
+type event struct {
+	Seq   int         `json:"seq"`
+	Type  string      `json:"type"`
+	Event string      `json:"event"`
+	Body  interface{} `json:"body,omitempty"`
+}
+
+// message is implemented by the messages the server sends, which get
+// their sequence number as they are written.
+type message interface {
+	setSeq(seq int)
+}
+
+func (r *response) setSeq(seq int) { r.Seq = seq }
+func (e *event) setSeq(seq int)    { e.Seq = seq }
+
+type session struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	mu  sync.Mutex // guards seq and writes to out
+	seq int
+
+	debugger   *debug.Debugger
+	path       string
+	program    *ast.Program
+	launched   bool
+	configured bool
+	started    bool
+	done       chan struct{}
+
+	// While the program is stopped, resume takes the action that
+	// continues it and refs maps variable references handed out to the
+	// client to the environments or values they stand for.
+	stopped   bool
+	resume    chan debug.Action
+	refs      map[int]interface{}
+	abandoned bool
+
+	// next is the action a resuming request continues the program with
+	// once its response is sent.
+	next *debug.Action
+}
+
+// Serve runs a debug session, reading requests from in and writing
+// responses and events to out, until the client disconnects.
+func Serve(in io.Reader, out io.Writer) error {
+	s := &session{
+		in:     bufio.NewReader(in),
+		out:    out,
+		resume: make(chan debug.Action),
+		done:   make(chan struct{}),
+	}
+	s.debugger = debug.New(s.pause)
+
+	for {
+		req, err := s.read()
+		if err == io.EOF {
+			s.abandon()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		body, err := s.handle(req)
+		if err != nil {
+			s.send(&response{Type: "response", RequestSeq: req.Seq, Command: req.Command, Success: false, Message: err.Error()})
+			continue
+		}
+		s.send(&response{Type: "response", RequestSeq: req.Seq, Command: req.Command, Success: true, Body: body})
+		if s.next != nil {
+			s.resume <- *s.next
+			s.next = nil
+		}
+
+		switch req.Command {
+		case "initialize":
+			s.event("initialized", nil)
+		case "launch", "configurationDone":
+			s.start()
+		case "disconnect":
+			return nil
+		}
+	}
+}
+
+func (s *session) read() (*request, error) {
+	headers, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length: %s", err)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+
+	req := &request{}
+	if err := json.Unmarshal(body, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (s *session) send(msg message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	msg.setSeq(s.seq)
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func (s *session) event(name string, body interface{}) {
+	s.send(&event{Type: "event", Event: name, Body: body})
+}
+
+func (s *session) handle(req *request) (interface{}, error) {
+	switch req.Command {
+	case "initialize":
+		return map[string]interface{}{
+			"supportsConfigurationDoneRequest": true,
+			"supportsEvaluateForHovers":        true,
+		}, nil
+	case "launch":
+		return nil, s.launch(req.Arguments)
+	case "setBreakpoints":
+		return s.setBreakpoints(req.Arguments)
+	case "configurationDone":
+		s.configured = true
+		return nil, nil
+	case "threads":
+		return map[string]interface{}{
+			"threads": []map[string]interface{}{{"id": threadID, "name": "main"}},
+		}, nil
+	case "stackTrace":
+		return s.stackTrace()
+	case "scopes":
+		return s.scopes(req.Arguments)
+	case "variables":
+		return s.variables(req.Arguments)
+	case "evaluate":
+		return s.evaluate(req.Arguments)
+	case "continue":
+		return map[string]interface{}{"allThreadsContinued": true}, s.proceed(debug.Continue)
+	case "next":
+		return nil, s.proceed(debug.StepOver)
+	case "stepIn":
+		return nil, s.proceed(debug.StepIn)
+	case "stepOut":
+		return nil, s.proceed(debug.StepOut)
+	case "disconnect":
+		s.abandon()
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("unsupported request %q", req.Command)
+}
+
+func (s *session) launch(arguments json.RawMessage) error {
+	args := struct {
+		Program     string `json:"program"`
+		StopOnEntry bool   `json:"stopOnEntry"`
+	}{}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return err
+	}
+
+	src, err := os.ReadFile(args.Program)
+	if err != nil {
+		return err
+	}
+
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return errors.New(strings.Join(p.Errors(), "; "))
+	}
+
+	s.path = args.Program
+	s.program = program
+	s.debugger.StopOnEntry = args.StopOnEntry
+	s.launched = true
+
+	return nil
+}
+
+// start runs the program once it is launched and configured.
+func (s *session) start() {
+	if !s.launched || !s.configured || s.started {
+		return
+	}
+	s.started = true
+
+	go func() {
+		defer close(s.done)
+
+		stdout := eval.Stdout
+		eval.Stdout = &output{s}
+		defer func() { eval.Stdout = stdout }()
+
+		env := object.NewEnv()
+		env.SetPath(s.path)
+		result, err := s.debugger.Run(s.program, env)
+		exitCode := 0
+		if err != nil {
+			exitCode = 1
+		} else if result != nil && result.Type() == object.ERR_OBJ {
+			s.event("output", map[string]interface{}{"category": "stderr", "output": result.Inspect() + "\n"})
+			exitCode = 1
+		}
+
+		s.event("exited", map[string]interface{}{"exitCode": exitCode})
+		s.event("terminated", nil)
+	}()
+}
+
+// output forwards what the program prints to the client.
+type output struct {
+	s *session
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	o.s.event("output", map[string]interface{}{"category": "stdout", "output": string(p)})
+	return len(p), nil
+}
+
+// pause runs on the program's goroutine each time it stops.
+func (s *session) pause(d *debug.Debugger, reason string) debug.Action {
+	s.mu.Lock()
+	if s.abandoned {
+		s.mu.Unlock()
+		return debug.Stop
+	}
+	s.stopped = true
+	s.refs = map[int]interface{}{}
+	s.mu.Unlock()
+
+	s.event("stopped", map[string]interface{}{"reason": reason, "threadId": threadID, "allThreadsStopped": true})
+
+	return <-s.resume
+}
+
+// proceed arranges for the stopped program to resume with action after
+// the current response.
+func (s *session) proceed(action debug.Action) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.stopped {
+		return errors.New("program is not stopped")
+	}
+	s.stopped = false
+	s.next = &action
+	return nil
+}
+
+// abandon stops a running program and waits for it to finish.
+func (s *session) abandon() {
+	if !s.started {
+		return
+	}
+
+	s.mu.Lock()
+	s.abandoned = true
+	stopped := s.stopped
+	s.stopped = false
+	s.mu.Unlock()
+
+	if stopped {
+		s.resume <- debug.Stop
+	}
+	<-s.done
+}
+
+func (s *session) setBreakpoints(arguments json.RawMessage) (interface{}, error) {
+	args := struct {
+		Source struct {
+			Path string `json:"path"`
+		} `json:"source"`
+		Breakpoints []struct {
+			Line int `json:"line"`
+		} `json:"breakpoints"`
+	}{}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	path := args.Source.Path
+	if path == "" {
+		path = s.path
+	}
+
+	// Breakpoints can only be verified once the program, or the module
+	// they are in, can be parsed.
+	program := s.program
+	if program != nil && !samePath(path, s.path) {
+		program = nil
+		if src, err := os.ReadFile(path); err == nil {
+			program = parser.New(lexer.New(string(src))).ParseProgram()
+		}
+	}
+	lines := map[int]bool{}
+	if program != nil {
+		ast.Inspect(program, func(n ast.Node) bool {
+			if _, ok := n.(ast.Statement); ok {
+				lines[ast.Start(n).Line] = true
+			}
+			return true
+		})
+	}
+
+	s.debugger.ClearBreakpoints(path)
+	breakpoints := []map[string]interface{}{}
+	for _, bp := range args.Breakpoints {
+		verified := program == nil || lines[bp.Line]
+		if verified {
+			s.debugger.SetBreakpoint(path, bp.Line)
+		}
+		breakpoints = append(breakpoints, map[string]interface{}{"verified": verified, "line": bp.Line})
+	}
+
+	return map[string]interface{}{"breakpoints": breakpoints}, nil
+}
+
+func (s *session) stackTrace() (interface{}, error) {
+	if err := s.checkStopped(); err != nil {
+		return nil, err
+	}
+
+	frames := []map[string]interface{}{}
+	for i, f := range s.debugger.Stack() {
+		column := 1
+		if f.Node != nil {
+			column = ast.Start(f.Node).Column
+		}
+		path := f.Path
+		if path == "" {
+			path = s.path
+		}
+		source := map[string]interface{}{"name": filepath.Base(path), "path": path}
+		frames = append(frames, map[string]interface{}{
+			"id":     i + 1,
+			"name":   f.Name,
+			"line":   f.Line(),
+			"column": column,
+			"source": source,
+		})
+	}
+
+	return map[string]interface{}{"stackFrames": frames, "totalFrames": len(frames)}, nil
+}
+
+func (s *session) scopes(arguments json.RawMessage) (interface{}, error) {
+	args := struct {
+		FrameID int `json:"frameId"`
+	}{}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+
+	env, err := s.frameEnv(args.FrameID)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := []map[string]interface{}{}
+	for depth := 0; env != nil; depth++ {
+		name := "Closure"
+		switch {
+		case env.Outer() == nil:
+			name = "Globals"
+		case depth == 0:
+			name = "Locals"
+		}
+		scopes = append(scopes, map[string]interface{}{
+			"name":               name,
+			"variablesReference": s.ref(env),
+			"expensive":          false,
+		})
+		env = env.Outer()
+	}
+
+	return map[string]interface{}{"scopes": scopes}, nil
+}
+
+func (s *session) variables(arguments json.RawMessage) (interface{}, error) {
+	args := struct {
+		VariablesReference int `json:"variablesReference"`
+	}{}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	if err := s.checkStopped(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	target, ok := s.refs[args.VariablesReference]
+	s.mu.Unlock()
+	if !ok {
+		return nil, errors.New("unknown variables reference")
+	}
+
+	vars := []map[string]interface{}{}
+	switch target := target.(type) {
+	case *object.Env:
+		for _, v := range debug.Variables(target) {
+			vars = append(vars, s.variable(v.Name, v.Value))
+		}
+	case *object.Arr:
+		for i, el := range target.Elements {
+			vars = append(vars, s.variable(fmt.Sprintf("[%d]", i), el))
+		}
+	case *object.Tuple:
+		for i, el := range target.Elements {
+			vars = append(vars, s.variable(fmt.Sprintf("[%d]", i), el))
+		}
+	case *object.Set:
+		for i, el := range target.Elements() {
+			vars = append(vars, s.variable(fmt.Sprintf("[%d]", i), el))
+		}
+	case *object.Struct:
+		for i, name := range target.Def.Fields {
+			vars = append(vars, s.variable(name, target.Values[i]))
+		}
+	case *object.Instance:
+		for _, name := range target.Names() {
+			vars = append(vars, s.variable(name, target.Fields[name]))
+		}
+	case *object.Hash:
+		for _, pair := range target.Pairs() {
+			vars = append(vars, s.variable(pair.Key.Inspect(), pair.Value))
+		}
+	case *object.ErrorValue:
+		stack := &object.Arr{}
+		for _, entry := range target.Err.Stack {
+			stack.Elements = append(stack.Elements, &object.Str{Value: entry})
+		}
+		vars = append(vars,
+			s.variable("kind", &object.Str{Value: target.Err.Kind}),
+			s.variable("message", &object.Str{Value: target.Err.Message}),
+			s.variable("line", &object.Int{Value: int64(target.Err.Line)}),
+			s.variable("column", &object.Int{Value: int64(target.Err.Column)}),
+			s.variable("stack", stack),
+		)
+	}
+
+	return map[string]interface{}{"variables": vars}, nil
+}
+
+func (s *session) evaluate(arguments json.RawMessage) (interface{}, error) {
+	args := struct {
+		Expression string `json:"expression"`
+		FrameID    int    `json:"frameId"`
+	}{}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	if err := s.checkStopped(); err != nil {
+		return nil, err
+	}
+
+	frame := 0
+	if args.FrameID > 0 {
+		frame = args.FrameID - 1
+	}
+
+	result, err := s.debugger.Evaluate(frame, args.Expression)
+	if err != nil {
+		return nil, err
+	}
+	if result.Type() == object.ERR_OBJ {
+		return nil, errors.New(result.Inspect())
+	}
+
+	v := s.variable("", result)
+	return map[string]interface{}{
+		"result":             v["value"],
+		"type":               v["type"],
+		"variablesReference": v["variablesReference"],
+	}, nil
+}
+
+// variable describes a value to the client, with a reference to expand
+// it by if it is an array, hash, struct or instance.
+func (s *session) variable(name string, value object.Object) map[string]interface{} {
+	ref := 0
+	switch value.(type) {
+	case *object.Arr, *object.Tuple, *object.Hash, *object.Set, *object.Struct, *object.Instance, *object.ErrorValue:
+		ref = s.ref(value)
+	}
+
+	// Clients show a value on one line, so keep the first line of one
+	// that holds a function and mark that it goes on.
+	text := value.Inspect()
+	if i := strings.Index(text, "\n"); i >= 0 {
+		text = text[:i] + " …"
+	}
+
+	return map[string]interface{}{
+		"name":               name,
+		"value":              text,
+		"type":               string(value.Type()),
+		"variablesReference": ref,
+	}
+}
+
+func (s *session) ref(target interface{}) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := len(s.refs) + 1
+	s.refs[id] = target
+	return id
+}
+
+func (s *session) frameEnv(id int) (*object.Env, error) {
+	if err := s.checkStopped(); err != nil {
+		return nil, err
+	}
+
+	stack := s.debugger.Stack()
+	if id < 1 || id > len(stack) {
+		return nil, errors.New("unknown frame")
+	}
+	return stack[id-1].Env, nil
+}
+
+func (s *session) checkStopped() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.stopped {
+		return errors.New("program is not stopped")
+	}
+	return nil
+}
+
+// samePath reports whether a and b name the same file.
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
@@ -0,0 +1,140 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSession replays testdata/session.txt against the server.
+func TestSession(t *testing.T) {
+	replay(t, "testdata/program.coff", "testdata/session.txt")
+}
+
+// TestModuleSession replays a session stopping in an imported module.
+func TestModuleSession(t *testing.T) {
+	replay(t, "testdata/modules/main.coff", "testdata/modules.txt")
+}
+
+// replay runs the session recorded in the file script, debugging the
+// program at path.
+func replay(t *testing.T, path, script string) {
+	program, err := filepath.Abs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines, err := os.ReadFile(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(inR, outW)
+		outW.Close()
+	}()
+
+	messages := make(chan map[string]interface{}, 100)
+	go receive(bufio.NewReader(outR), messages)
+
+	for n, line := range strings.Split(string(lines), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.ReplaceAll(line, "$PROGRAM", program)
+		line = strings.ReplaceAll(line, "$DIR", filepath.Dir(program))
+		direction, text := line[:1], strings.TrimSpace(line[1:])
+
+		switch direction {
+		case ">":
+			fmt.Fprintf(inW, "Content-Length: %d\r\n\r\n%s", len(text), text)
+		case "<":
+			expected := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(text), &expected); err != nil {
+				t.Fatalf("%s:%d: %s", script, n+1, err)
+			}
+
+			select {
+			case msg, ok := <-messages:
+				if !ok {
+					t.Fatalf("%s:%d: server closed the connection", script, n+1)
+				}
+				if !matches(expected, msg) {
+					got, _ := json.Marshal(msg)
+					t.Fatalf("%s:%d: unexpected message\nexpected: %s\ngot:      %s", script, n+1, text, got)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s:%d: timed out waiting for %s", script, n+1, text)
+			}
+		default:
+			t.Fatalf("%s:%d: line must start with > or <", script, n+1)
+		}
+	}
+
+	if err := <-done; err != nil {
+		t.Errorf("Serve returned error: %s", err)
+	}
+}
+
+func receive(out *bufio.Reader, messages chan map[string]interface{}) {
+	defer close(messages)
+	for {
+		headers, err := textproto.NewReader(out).ReadMIMEHeader()
+		if err != nil {
+			return
+		}
+		length, _ := strconv.Atoi(headers.Get("Content-Length"))
+		body := make([]byte, length)
+		if _, err := io.ReadFull(out, body); err != nil {
+			return
+		}
+
+		msg := map[string]interface{}{}
+		if err := json.Unmarshal(body, &msg); err != nil {
+			return
+		}
+		messages <- msg
+	}
+}
+
+// matches reports whether got has every field in expected. Arrays must
+// have the same length, with each element matching.
+func matches(expected, got interface{}) bool {
+	switch expected := expected.(type) {
+	case map[string]interface{}:
+		got, ok := got.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, value := range expected {
+			if !matches(value, got[key]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		got, ok := got.([]interface{})
+		if !ok || len(got) != len(expected) {
+			return false
+		}
+		for i := range expected {
+			if !matches(expected[i], got[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(expected, got)
+}
@@ -0,0 +1,292 @@
+// Package debug runs CoffLang programs under the control of a debugger
+// frontend: it stops at breakpoints and after steps, and lets the
+// frontend inspect the call stack and evaluate code while stopped.
+package debug
+
+import (
+	"coff-src/src/coff/ast"
+	"coff-src/src/coff/eval"
+	"coff-src/src/coff/lexer"
+	"coff-src/src/coff/object"
+	"coff-src/src/coff/parser"
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Action tells a stopped program how to go on.
+type Action int
+
+const (
+	Continue Action = iota
+	StepIn          // stop at the next statement
+	StepOver        // stop at the next statement not in a function called from here
+	StepOut         // stop at the next statement after the current function returns
+	Stop            // abandon the program
+)
+
+// Reasons passed to Pause.
+const (
+	ENTRY      = "entry"
+	BREAKPOINT = "breakpoint"
+	STEP       = "step"
+)
+
+// ErrStopped is returned by Run when the frontend abandons the program.
+var ErrStopped = errors.New("program stopped by debugger")
+
+// Location is a line in a source file. Path is "" for a program that
+// was not read from a file.
+type Location struct {
+	Path string
+	Line int
+}
+
+// Frame is an active function call.
+type Frame struct {
+	Name string        // the function's name, "<main>" for the program itself
+	Env  *object.Env   // the environment the current statement runs in
+	Node ast.Statement // the current statement, nil before the first one
+	Path string        // the file the current statement is in
+	at   Location      // the last statement stopped at or passed
+}
+
+// Line returns the line of the frame's current statement.
+func (f *Frame) Line() int {
+	if f.Node == nil {
+		return 0
+	}
+	return ast.Start(f.Node).Line
+}
+
+type Debugger struct {
+	// Pause is called on the goroutine running the program whenever it
+	// stops. The program stays stopped until Pause returns.
+	Pause func(d *Debugger, reason string) Action
+
+	// StopOnEntry makes Run stop before the first statement.
+	StopOnEntry bool
+
+	mu          sync.Mutex // guards breakpoints, which may change while running
+	breakpoints map[Location]bool // by absolute path
+	paths       map[string]string // absolute paths of the files run so far
+	frames      []*Frame
+	action      Action
+	depth       int // stack depth when action was chosen
+	entry       bool
+	evaluating  bool
+}
+
+func New(pause func(d *Debugger, reason string) Action) *Debugger {
+	return &Debugger{Pause: pause, breakpoints: map[Location]bool{}, paths: map[string]string{}}
+}
+
+// SetBreakpoint sets a breakpoint on line of the file path.
+func (d *Debugger) SetBreakpoint(path string, line int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.breakpoints[Location{absolute(path), line}] = true
+}
+
+func (d *Debugger) ClearBreakpoint(path string, line int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.breakpoints, Location{absolute(path), line})
+}
+
+// ClearBreakpoints removes the breakpoints in the file path.
+func (d *Debugger) ClearBreakpoints(path string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	path = absolute(path)
+	for bp := range d.breakpoints {
+		if bp.Path == path {
+			delete(d.breakpoints, bp)
+		}
+	}
+}
+
+// Breakpoints returns the breakpoints ordered by file and line, with
+// absolute paths.
+func (d *Debugger) Breakpoints() []Location {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	breakpoints := []Location{}
+	for bp := range d.breakpoints {
+		breakpoints = append(breakpoints, bp)
+	}
+	sort.Slice(breakpoints, func(i, j int) bool {
+		a, b := breakpoints[i], breakpoints[j]
+		return a.Path < b.Path || a.Path == b.Path && a.Line < b.Line
+	})
+	return breakpoints
+}
+
+// absolute returns path made absolute, so that the same file named in
+// different ways has one breakpoint key. "" stays "".
+func absolute(path string) string {
+	if path == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// Stack returns the active calls, innermost first.
+func (d *Debugger) Stack() []*Frame {
+	stack := []*Frame{}
+	for i := len(d.frames) - 1; i >= 0; i-- {
+		stack = append(stack, d.frames[i])
+	}
+	return stack
+}
+
+// Run evaluates program in env, stopping as the breakpoints and the
+// actions returned by Pause direct.
+func (d *Debugger) Run(program *ast.Program, env *object.Env) (result object.Object, err error) {
+	d.frames = []*Frame{{Name: "<main>", Env: env}}
+	d.action = Continue
+	d.entry = d.StopOnEntry
+
+	remove := eval.AddHooks(&eval.Hooks{Node: d.node, Call: d.call, Return: d.ret})
+	defer remove()
+
+	defer func() {
+		if r := recover(); r != nil {
+			if r != stop {
+				panic(r)
+			}
+			result, err = nil, ErrStopped
+		}
+	}()
+
+	return eval.Eval(program, env), nil
+}
+
+// stop is the panic value that unwinds an abandoned program.
+var stop = &struct{ name string }{"stop"}
+
+func (d *Debugger) node(node ast.Node, env *object.Env) {
+	stmt, ok := node.(ast.Statement)
+	if !ok || d.evaluating {
+		return
+	}
+	if _, ok := node.(*ast.BlockStatement); ok {
+		return
+	}
+
+	frame := d.frames[len(d.frames)-1]
+	frame.Node = stmt
+	frame.Env = env
+	frame.Path = env.Path()
+	at := Location{d.absolute(frame.Path), frame.Line()}
+
+	reason := ""
+	switch {
+	case d.entry:
+		reason = ENTRY
+		d.entry = false
+	case d.action == StepIn:
+		reason = STEP
+	case d.action == StepOver && len(d.frames) <= d.depth:
+		reason = STEP
+	case d.action == StepOut && len(d.frames) < d.depth:
+		reason = STEP
+	}
+	if d.hasBreakpoint(at) && at != frame.at && reason != ENTRY {
+		reason = BREAKPOINT
+	}
+	frame.at = at
+
+	if reason == "" {
+		return
+	}
+
+	d.action = d.Pause(d, reason)
+	d.depth = len(d.frames)
+	if d.action == Stop {
+		panic(stop)
+	}
+}
+
+func (d *Debugger) hasBreakpoint(at Location) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.breakpoints[at]
+}
+
+// absolute returns the absolute form of path, remembering it so that
+// each statement run does not ask the file system again.
+func (d *Debugger) absolute(path string) string {
+	abs, ok := d.paths[path]
+	if !ok {
+		abs = absolute(path)
+		d.paths[path] = abs
+	}
+	return abs
+}
+
+func (d *Debugger) call(fn *object.Function, args []object.Object, env *object.Env) {
+	if d.evaluating {
+		return
+	}
+
+	name := fn.Name
+	if name == "" {
+		name = "<anonymous>"
+	}
+	d.frames = append(d.frames, &Frame{Name: name, Env: env})
+}
+
+func (d *Debugger) ret(fn *object.Function, result object.Object) {
+	if d.evaluating {
+		return
+	}
+	d.frames = d.frames[:len(d.frames)-1]
+}
+
+// Evaluate evaluates src in the environment of the given frame, counted
+// from the innermost one, while the program is stopped.
+func (d *Debugger) Evaluate(frame int, src string) (object.Object, error) {
+	stack := d.Stack()
+	if frame < 0 || frame >= len(stack) {
+		return nil, errors.New("no such frame")
+	}
+
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, errors.New(strings.Join(p.Errors(), "; "))
+	}
+
+	d.evaluating = true
+	defer func() { d.evaluating = false }()
+
+	result := eval.Eval(program, stack[frame].Env)
+	if result == nil {
+		result = eval.NULL
+	}
+	return result, nil
+}
+
+// Variable is a name bound in an environment.
+type Variable struct {
+	Name  string
+	Value object.Object
+}
+
+// Variables returns the bindings made directly in env, sorted by name.
+func Variables(env *object.Env) []Variable {
+	vars := []Variable{}
+	for _, name := range env.Names() {
+		value, _ := env.Get(name)
+		vars = append(vars, Variable{Name: name, Value: value})
+	}
+	return vars
+}
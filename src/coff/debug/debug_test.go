@@ -0,0 +1,242 @@
+package debug
+
+import (
+	"bytes"
+	"coff-src/src/coff/eval"
+	"coff-src/src/coff/lexer"
+	"coff-src/src/coff/object"
+	"coff-src/src/coff/parser"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testProgram = `def base = 10;
+def add = fun(a, b) {
+	def sum = a + b;
+	ret sum + base;
+};
+def twice = fun(x) {
+	def y = add(x, x);
+	y
+};
+def r = twice(2);
+r;
+`
+
+// stops runs testProgram, answering each stop with the next of actions,
+// and returns the function and line of every stop.
+func stops(t *testing.T, breakpoints []int, actions ...Action) []string {
+	p := parser.New(lexer.New(testProgram))
+	program := p.ParseProgram()
+
+	seen := []string{}
+	d := New(func(d *Debugger, reason string) Action {
+		frame := d.Stack()[0]
+		seen = append(seen, fmt.Sprintf("%s:%d:%s", frame.Name, frame.Line(), reason))
+		if len(actions) == 0 {
+			return Continue
+		}
+		action := actions[0]
+		actions = actions[1:]
+		return action
+	})
+	d.StopOnEntry = true
+	for _, line := range breakpoints {
+		d.SetBreakpoint("", line)
+	}
+
+	result, err := d.Run(program, object.NewEnv())
+	if err == nil {
+		testInt(t, result, 14)
+	}
+
+	return seen
+}
+
+func TestStepping(t *testing.T) {
+	tests := []struct {
+		breakpoints []int
+		actions     []Action
+		expected    []string
+	}{
+		{
+			nil,
+			[]Action{Continue},
+			[]string{"<main>:1:entry"},
+		},
+		{
+			[]int{3},
+			[]Action{Continue, Continue},
+			[]string{"<main>:1:entry", "add:3:breakpoint"},
+		},
+		{
+			nil,
+			[]Action{StepOver, StepOver, StepOver, StepOver, Continue},
+			[]string{"<main>:1:entry", "<main>:2:step", "<main>:6:step", "<main>:10:step", "<main>:11:step"},
+		},
+		{
+			[]int{7},
+			[]Action{Continue, StepIn, StepIn, StepOut, StepOut, Continue},
+			[]string{"<main>:1:entry", "twice:7:breakpoint", "add:3:step", "add:4:step", "twice:8:step", "<main>:11:step"},
+		},
+		{
+			[]int{4},
+			[]Action{Continue, Stop},
+			[]string{"<main>:1:entry", "add:4:breakpoint"},
+		},
+	}
+
+	for _, tt := range tests {
+		got := stops(t, tt.breakpoints, tt.actions...)
+		if strings.Join(got, " ") != strings.Join(tt.expected, " ") {
+			t.Errorf("wrong stops. expected=%v, got=%v", tt.expected, got)
+		}
+	}
+}
+
+func TestBreakpointsInModules(t *testing.T) {
+	src, err := os.ReadFile("testdata/main.coff")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		breakpoints []Location
+		expected    []string
+	}{
+		{
+			[]Location{{"testdata/main.coff", 2}},
+			[]string{"<main>:main.coff:2"},
+		},
+		{
+			[]Location{{"testdata/lib.coff", 2}},
+			[]string{"double:lib.coff:2"},
+		},
+		{
+			[]Location{{"testdata/lib.coff", 2}, {"testdata/main.coff", 2}, {"testdata/main.coff", 3}},
+			[]string{"<main>:main.coff:2", "double:lib.coff:2", "<main>:main.coff:3"},
+		},
+	}
+
+	for _, tt := range tests {
+		seen := []string{}
+		d := New(func(d *Debugger, reason string) Action {
+			frame := d.Stack()[0]
+			seen = append(seen, fmt.Sprintf("%s:%s:%d", frame.Name, filepath.Base(frame.Path), frame.Line()))
+			return Continue
+		})
+		for _, bp := range tt.breakpoints {
+			d.SetBreakpoint(bp.Path, bp.Line)
+		}
+
+		env := object.NewEnv()
+		env.SetPath("testdata/main.coff")
+		result, err := d.Run(parser.New(lexer.New(string(src))).ParseProgram(), env)
+		if err != nil {
+			t.Fatalf("Run returned error: %s", err)
+		}
+		testInt(t, result, 5)
+
+		if strings.Join(seen, " ") != strings.Join(tt.expected, " ") {
+			t.Errorf("wrong stops. expected=%v, got=%v", tt.expected, seen)
+		}
+	}
+}
+
+func TestStackAfterStop(t *testing.T) {
+	p := parser.New(lexer.New(testProgram))
+	d := New(func(d *Debugger, reason string) Action { return Stop })
+	d.SetBreakpoint("", 4)
+	if _, err := d.Run(p.ParseProgram(), object.NewEnv()); err != ErrStopped {
+		t.Fatalf("expected the program to be stopped. got=%v", err)
+	}
+
+	p = parser.New(lexer.New("def g = fun() { 1 + true };\ng();"))
+	result := eval.Eval(p.ParseProgram(), object.NewEnv())
+	err, ok := result.(*object.Error)
+	if !ok {
+		t.Fatalf("result is not Error. got=%T (%+v)", result, result)
+	}
+	expected := []string{"g (1:17)", "[main] (2:1)"}
+	if strings.Join(err.Stack, " ") != strings.Join(expected, " ") {
+		t.Errorf("wrong stack. expected=%q, got=%q", expected, err.Stack)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	p := parser.New(lexer.New(testProgram))
+	program := p.ParseProgram()
+
+	d := New(func(d *Debugger, reason string) Action {
+		if len(d.Stack()) != 3 {
+			t.Fatalf("wrong stack depth. got=%d", len(d.Stack()))
+		}
+
+		result, err := d.Evaluate(0, "a + b + base")
+		if err != nil {
+			t.Fatalf("Evaluate returned error: %s", err)
+		}
+		testInt(t, result, 14)
+
+		result, err = d.Evaluate(1, "x")
+		if err != nil {
+			t.Fatalf("Evaluate returned error: %s", err)
+		}
+		testInt(t, result, 2)
+
+		if _, err := d.Evaluate(0, "a +"); err == nil {
+			t.Errorf("expected a parse error")
+		}
+
+		vars := Variables(d.Stack()[0].Env)
+		if len(vars) != 2 || vars[0].Name != "a" || vars[1].Name != "b" {
+			t.Errorf("wrong locals: %v", vars)
+		}
+		return Continue
+	})
+	d.SetBreakpoint("", 3)
+
+	result, err := d.Run(program, object.NewEnv())
+	if err != nil {
+		t.Fatalf("Run returned error: %s", err)
+	}
+	testInt(t, result, 14)
+}
+
+func TestTerminal(t *testing.T) {
+	input := "b 3\nc\nbt\nlocals\np sum\np a * 100\nout\nq\n"
+	out := &bytes.Buffer{}
+
+	if err := RunTerminal("test.coff", testProgram, strings.NewReader(input), out); err != nil {
+		t.Fatalf("RunTerminal returned error: %s", err)
+	}
+
+	expected := []string{
+		"stopped at test.coff:1 in <main> (entry)",
+		"breakpoint set at test.coff:3",
+		"stopped at test.coff:3 in add (breakpoint)",
+		"* 0 add at test.coff:3\n  1 twice at test.coff:7\n  2 <main> at test.coff:10",
+		"locals:\n  a = 2\n  b = 2\nglobals:\n  add = fn(a, b) { ... }\n  base = 10\n  twice = fn(x) { ... }",
+		"identifier is not found: sum",
+		"200",
+		"stopped at test.coff:8 in twice (step)",
+		"program abandoned",
+	}
+
+	output := out.String()
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Errorf("output does not contain %q:\n%s", e, output)
+		}
+	}
+}
+
+func testInt(t *testing.T, obj object.Object, expected int64) {
+	result, ok := obj.(*object.Int)
+	if !ok || result.Value != expected {
+		t.Errorf("wrong result. expected=%d, got=%v", expected, obj)
+	}
+}
@@ -0,0 +1,233 @@
+package debug
+
+import (
+	"bufio"
+	"coff-src/src/coff/eval"
+	"coff-src/src/coff/lexer"
+	"coff-src/src/coff/object"
+	"coff-src/src/coff/parser"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const PROMPT = "(coff) "
+
+const help = `commands:
+  break LINE, b LINE   set a breakpoint, in another file with FILE:LINE
+  clear LINE           remove a breakpoint, in another file with FILE:LINE
+  breakpoints          list breakpoints
+  continue, c          run until the next breakpoint
+  step, s              step to the next statement, into calls
+  next, n              step to the next statement, over calls
+  out, o               step out of the current function
+  stack, bt            show the call stack
+  frame N, f N         select frame N for locals and print
+  locals, l            show the variables visible in the selected frame
+  print EXPR, p EXPR   evaluate EXPR in the selected frame
+  list                 show the source around the current line
+  quit, q              abandon the program
+`
+
+// terminal is a command line frontend reading commands from in.
+type terminal struct {
+	name    string
+	sources map[string][]string // the lines of each file shown so far
+	in      *bufio.Scanner
+	out   io.Writer
+	frame int
+}
+
+// RunTerminal debugs the program in src, taking commands from in and
+// writing to out. The program starts stopped at its first statement.
+func RunTerminal(name string, src string, in io.Reader, out io.Writer) error {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return errors.New(strings.Join(p.Errors(), "\n"))
+	}
+
+	t := &terminal{
+		name:    name,
+		sources: map[string][]string{name: strings.Split(src, "\n")},
+		in:      bufio.NewScanner(in),
+		out:     out,
+	}
+
+	d := New(t.pause)
+	d.StopOnEntry = true
+
+	env := object.NewEnv()
+	env.SetPath(name)
+	result, err := d.Run(program, env)
+	if err == ErrStopped {
+		fmt.Fprintln(out, "program abandoned")
+		return nil
+	}
+	if result != nil && result.Type() == object.ERR_OBJ {
+		fmt.Fprintln(out, result.Inspect())
+	}
+	fmt.Fprintln(out, "program finished")
+
+	return nil
+}
+
+func (t *terminal) pause(d *Debugger, reason string) Action {
+	t.frame = 0
+	frame := d.Stack()[0]
+	fmt.Fprintf(t.out, "stopped at %s:%d in %s (%s)\n", t.file(frame.Path), frame.Line(), frame.Name, reason)
+	t.show(frame.Path, frame.Line(), 0)
+
+	for {
+		fmt.Fprint(t.out, PROMPT)
+		if !t.in.Scan() {
+			fmt.Fprintln(t.out)
+			return Stop
+		}
+
+		fields := strings.Fields(t.in.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		command, args := fields[0], fields[1:]
+
+		switch command {
+		case "continue", "c":
+			return Continue
+		case "step", "s":
+			return StepIn
+		case "next", "n":
+			return StepOver
+		case "out", "o":
+			return StepOut
+		case "quit", "q":
+			return Stop
+		case "break", "b":
+			if at, ok := t.location(args); ok {
+				d.SetBreakpoint(at.Path, at.Line)
+				fmt.Fprintf(t.out, "breakpoint set at %s:%d\n", at.Path, at.Line)
+			}
+		case "clear":
+			if at, ok := t.location(args); ok {
+				d.ClearBreakpoint(at.Path, at.Line)
+			}
+		case "breakpoints":
+			for _, bp := range d.Breakpoints() {
+				fmt.Fprintf(t.out, "%s:%d\n", t.file(bp.Path), bp.Line)
+			}
+		case "stack", "bt":
+			for i, f := range d.Stack() {
+				marker := " "
+				if i == t.frame {
+					marker = "*"
+				}
+				fmt.Fprintf(t.out, "%s %d %s at %s:%d\n", marker, i, f.Name, t.file(f.Path), f.Line())
+			}
+		case "frame", "f":
+			n, err := strconv.Atoi(strings.Join(args, ""))
+			if err != nil || n < 0 || n >= len(d.Stack()) {
+				fmt.Fprintln(t.out, "usage: frame N, with N as listed by stack")
+				continue
+			}
+			t.frame = n
+		case "locals", "l":
+			t.locals(d.Stack()[t.frame].Env)
+		case "print", "p":
+			result, err := d.Evaluate(t.frame, strings.Join(args, " "))
+			if err != nil {
+				fmt.Fprintln(t.out, err)
+				continue
+			}
+			fmt.Fprintln(t.out, result.Inspect())
+		case "list":
+			frame := d.Stack()[t.frame]
+			t.show(frame.Path, frame.Line(), 3)
+		case "help", "h":
+			fmt.Fprint(t.out, help)
+		default:
+			fmt.Fprintf(t.out, "unknown command %q, try help\n", command)
+		}
+	}
+}
+
+// locals prints the bindings of env and of each environment enclosing
+// it, innermost first.
+func (t *terminal) locals(env *object.Env) {
+	for depth := 0; env != nil; depth++ {
+		switch {
+		case env.Outer() == nil:
+			fmt.Fprintln(t.out, "globals:")
+		case depth == 0:
+			fmt.Fprintln(t.out, "locals:")
+		default:
+			fmt.Fprintln(t.out, "enclosing:")
+		}
+
+		for _, v := range Variables(env) {
+			fmt.Fprintf(t.out, "  %s = %s\n", v.Name, inspect(v.Value))
+		}
+		env = env.Outer()
+	}
+}
+
+// show prints the source lines of the file path within context lines of
+// line.
+func (t *terminal) show(path string, line int, context int) {
+	lines, ok := t.sources[t.file(path)]
+	if !ok {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return
+		}
+		lines = strings.Split(string(src), "\n")
+		t.sources[t.file(path)] = lines
+	}
+
+	for l := line - context; l <= line+context; l++ {
+		if l < 1 || l > len(lines) {
+			continue
+		}
+		marker := " "
+		if l == line {
+			marker = ">"
+		}
+		fmt.Fprintf(t.out, "%s %4d  %s\n", marker, l, lines[l-1])
+	}
+}
+
+// file returns the name path is shown by: the program's own name for
+// the program, otherwise as in error messages.
+func (t *terminal) file(path string) string {
+	if path == "" || absolute(path) == absolute(t.name) {
+		return t.name
+	}
+	return eval.DisplayPath(path)
+}
+
+// location parses LINE, a line of the program, or FILE:LINE.
+func (t *terminal) location(args []string) (Location, bool) {
+	if len(args) == 1 {
+		path, line := t.name, args[0]
+		if i := strings.LastIndex(line, ":"); i >= 0 {
+			path, line = line[:i], line[i+1:]
+		}
+		if n, err := strconv.Atoi(line); err == nil && n > 0 {
+			return Location{path, n}, true
+		}
+	}
+	fmt.Fprintln(t.out, "expected a line number or FILE:LINE")
+	return Location{}, false
+}
+
+// inspect shortens multi-line values such as functions to their first
+// line.
+func inspect(obj object.Object) string {
+	s := obj.Inspect()
+	if i := strings.Index(s, "\n"); i >= 0 {
+		return s[:i] + " ... }"
+	}
+	return s
+}
@@ -0,0 +1,123 @@
+package eval
+
+import (
+	"coff-src/src/coff/ast"
+	"coff-src/src/coff/object"
+	"strings"
+)
+
+// The assertion builtins used by test files. They fail by returning an
+// error, which ends the test like any other error. They are registered
+// in init because assertError calls back into the evaluator.
+func init() {
+	stds["assert"] = &object.Std{
+		Signature: "assert(cond, msg?)",
+		Doc:       "Fails with msg unless cond is truthy.",
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+			if isTruthy(args[0]) {
+				return NULL
+			}
+			return assertionFailed(args[1:], "assertion failed")
+		},
+	}
+
+	stds["assertEq"] = &object.Std{
+		Signature: "assertEq(actual, expected, msg?)",
+		Doc:       "Fails unless actual equals expected, comparing arrays and hashes element by element.",
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) != 2 && len(args) != 3 {
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=2 or 3", len(args))
+			}
+			if object.Equal(args[0], args[1]) {
+				return NULL
+			}
+			return assertionFailed(args[2:], "expected %s, got %s", args[1].Inspect(), args[0].Inspect())
+		},
+	}
+
+	stds["assertError"] = &object.Std{
+		Signature: "assertError(fn, msg?)",
+		Doc:       "Calls fn without arguments and fails unless it returns an error containing msg.",
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+			// Errors from setting up the call itself, such as calling
+			// with the wrong number of arguments, must not pass for the
+			// error fn was expected to raise.
+			required, ok := requiredArgs(args[0])
+			if !ok {
+				return newError(object.TYPE_ERROR, "argument to `assertError` must be callable, got %s", args[0].Type())
+			}
+			if required != 0 {
+				return newError(object.TYPE_ERROR, "argument to `assertError` must take no arguments, got %d required parameters", required)
+			}
+
+			result := applyFunction(args[0], nil)
+			if result == nil {
+				result = NULL // fn ended with a statement that has no value
+			}
+			err, ok := result.(*object.Error)
+			if !ok {
+				return newError(object.ASSERTION_ERROR, "expected an error, got %s", result.Inspect())
+			}
+			if len(args) == 2 {
+				msg, ok := args[1].(*object.Str)
+				if !ok {
+					return newError(object.TYPE_ERROR, "argument to `assertError` must be STR, got %s", args[1].Type())
+				}
+				if !strings.Contains(err.Message, msg.Value) {
+					return newError(object.ASSERTION_ERROR, "expected an error containing %q, got %q", msg.Value, err.Message)
+				}
+			}
+			return NULL
+		},
+	}
+
+	stds["fail"] = &object.Std{
+		Signature: "fail(msg?)",
+		Doc:       "Fails with msg.",
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) > 1 {
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=0 or 1", len(args))
+			}
+			return assertionFailed(args, "failed")
+		},
+	}
+}
+
+// requiredArgs returns the number of arguments fn cannot be called
+// without, and false if fn cannot be called at all.
+func requiredArgs(fn object.Object) (int, bool) {
+	switch fn := fn.(type) {
+	case *object.Function:
+		return ast.Required(fn.Parameters), true
+	case *object.BoundMethod:
+		return ast.Required(fn.Fun.Parameters), true
+	case *object.Class:
+		if init, _, ok := fn.Method("init"); ok {
+			return ast.Required(init.Parameters), true
+		}
+		return 0, true
+	case *object.StructType:
+		return len(fn.Fields), true
+	case *object.Std:
+		return 0, true
+	}
+	return 0, false
+}
+
+// assertionFailed returns the error of a failed assertion: the message
+// the caller passed in msg if any, the default one otherwise.
+func assertionFailed(msg []object.Object, format string, a ...interface{}) *object.Error {
+	if len(msg) == 1 {
+		if s, ok := msg[0].(*object.Str); ok {
+			return newError(object.ASSERTION_ERROR, "%s", s.Value)
+		}
+		return newError(object.ASSERTION_ERROR, "%s", msg[0].Inspect())
+	}
+	return newError(object.ASSERTION_ERROR, format, a...)
+}
@@ -0,0 +1,107 @@
+package eval
+
+import (
+	"coff-src/src/coff/ast"
+	"coff-src/src/coff/object"
+)
+
+func evalClassStatement(node *ast.ClassStatement, env *object.Env) object.Object {
+	class := &object.Class{Name: node.Name.Value, Methods: map[string]*object.Function{}}
+
+	if node.Super != nil {
+		super := evalIdentifier(node.Super, env)
+		if isError(super) {
+			return super
+		}
+		superClass, ok := super.(*object.Class)
+		if !ok {
+			return newError(object.TYPE_ERROR, "%s cannot extend %s: not a class", class.Name, super.Type())
+		}
+		class.Super = superClass
+	}
+
+	for _, m := range node.Methods {
+		class.Methods[m.Name] = allocated(&object.Function{
+			Name:       class.Name + "." + m.Name,
+			Parameters: m.Parameters,
+			Body:       m.Body,
+			Env:        env,
+		}).(*object.Function)
+	}
+	env.Set(class.Name, class)
+
+	return nil
+}
+
+// newInstance calls class: it creates an instance and runs its init
+// method, if any, with args.
+func newInstance(class *object.Class, args []object.Object) object.Object {
+	instance := object.NewInstance(class)
+	allocated(instance)
+
+	init, defining, ok := class.Method("init")
+	if !ok {
+		if len(args) != 0 {
+			return newError(object.ARGUMENT_ERROR, "wrong number of arguments to %s. got=%d, want=0", class.Name, len(args))
+		}
+		return instance
+	}
+
+	result := applyMethod(&object.BoundMethod{Receiver: instance, Fun: init, Class: defining}, args)
+	if isError(result) {
+		return result
+	}
+
+	return instance
+}
+
+func applyMethod(method *object.BoundMethod, args []object.Object) object.Object {
+	env, err := extendFunctionEnv(method.Fun, args)
+	if err != nil {
+		return err
+	}
+	env.Set("self", method.Receiver)
+	if method.Class.Super != nil {
+		env.Set("super", &object.Super{Receiver: method.Receiver, Class: method.Class.Super})
+	}
+
+	return callFunction(method.Fun, args, env)
+}
+
+// instanceMember returns the field called name, or the method called
+// name bound to instance.
+func instanceMember(instance *object.Instance, name string) object.Object {
+	if value, ok := instance.Fields[name]; ok {
+		return value
+	}
+	if fn, defining, ok := instance.Class.Method(name); ok {
+		return &object.BoundMethod{Receiver: instance, Fun: fn, Class: defining}
+	}
+
+	return newError(object.MEMBER_ERROR, "%s has no member %s", instance.Class.Name, name)
+}
+
+// superMember returns the method called name of the superclass, bound to
+// the receiver.
+func superMember(super *object.Super, name string) object.Object {
+	if fn, defining, ok := super.Class.Method(name); ok {
+		return &object.BoundMethod{Receiver: super.Receiver, Fun: fn, Class: defining}
+	}
+
+	return newError(object.MEMBER_ERROR, "%s has no method %s", super.Class.Name, name)
+}
+
+// evalIsExpression reports whether left is an instance of right, a class
+// or a struct type.
+func evalIsExpression(left, right object.Object) object.Object {
+	switch right := right.(type) {
+	case *object.Class:
+		instance, ok := left.(*object.Instance)
+		return nativeBoolToBoolObject(ok && instance.Class.Extends(right))
+	case *object.StructType:
+		s, ok := left.(*object.Struct)
+		return nativeBoolToBoolObject(ok && s.Def == right)
+	default:
+		return newError(object.TYPE_ERROR, "right operand of is must be a class or struct, got %s", right.Type())
+	}
+}
@@ -0,0 +1,179 @@
+package eval
+
+import (
+	"coff-src/src/coff/ast"
+	"coff-src/src/coff/object"
+	"fmt"
+	"strings"
+)
+
+// frame is a call in progress, recorded for the stacks of errors.
+type frame struct {
+	callee object.Object
+	call   ast.Node // the call expression
+	path   string   // the file the call is in
+}
+
+// frames holds the calls in progress, the innermost last.
+var frames []frame
+
+// evalCall applies function to args as the call expression node, with
+// the call recorded in frames while it runs. The frame is removed even
+// if the call is abandoned by a panic, as the debugger does on Stop.
+func evalCall(function object.Object, args []object.Object, node *ast.CallExpression, env *object.Env) object.Object {
+	depth := len(frames)
+	frames = append(frames, frame{callee: function, call: node, path: env.Path()})
+	defer func() { frames = frames[:depth] }()
+
+	return applyFunction(function, args)
+}
+
+// evalThrowStatement raises the value thrown. An error value is raised
+// again with its kind, message and value, but at the position and with
+// the stack of this throw; any other value becomes an error of kind ERROR.
+func evalThrowStatement(node *ast.ThrowStatement, env *object.Env) object.Object {
+	val := Eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	switch val := val.(type) {
+	case *object.ErrorValue:
+		return &object.Error{Kind: val.Err.Kind, Message: val.Err.Message, Value: val.Err.Value}
+	case *object.Str:
+		return &object.Error{Kind: object.ERROR, Message: val.Value, Value: val}
+	default:
+		return &object.Error{Kind: object.ERROR, Message: val.Inspect(), Value: val}
+	}
+}
+
+// evalTryExpression evaluates the body and, if it fails, the catch block
+// with the error bound to its parameter. The finally block runs last
+// either way; an error or ret in it replaces the result.
+func evalTryExpression(node *ast.TryExpression, env *object.Env) object.Object {
+	result := Eval(node.Body, env)
+
+	if err, ok := result.(*object.Error); ok && node.Catch != nil {
+		catchEnv := object.NewEnclosedEnv(env)
+		if node.Param != nil {
+			catchEnv.Set(node.Param.Value, &object.ErrorValue{Err: err})
+		}
+		result = Eval(node.Catch, catchEnv)
+	}
+
+	if node.Finally != nil {
+		done := Eval(node.Finally, env)
+		if done != nil && (done.Type() == object.ERR_OBJ || done.Type() == object.RET_VAL_OBJ) {
+			return done
+		}
+	}
+
+	return result
+}
+
+// errorMember returns the member of a caught error called name.
+func errorMember(ev *object.ErrorValue, name string) object.Object {
+	err := ev.Err
+	switch name {
+	case "message":
+		return allocated(&object.Str{Value: err.Message})
+	case "kind":
+		return allocated(&object.Str{Value: err.Kind})
+	case "path":
+		if err.Path == "" {
+			return NULL
+		}
+		return allocated(&object.Str{Value: DisplayPath(err.Path)})
+	case "line":
+		return &object.Int{Value: int64(err.Line)}
+	case "column":
+		return &object.Int{Value: int64(err.Column)}
+	case "stack":
+		elements := []object.Object{}
+		for _, entry := range err.Stack {
+			elements = append(elements, allocated(&object.Str{Value: entry}))
+		}
+		return allocated(&object.Arr{Elements: elements})
+	case "value":
+		if err.Value == nil {
+			return NULL
+		}
+		return err.Value
+	}
+
+	return newError(object.MEMBER_ERROR, "error has no member %s", name)
+}
+
+// raised records that err was raised by node: its position and the
+// calls in progress.
+func raised(err *object.Error, node ast.Node, env *object.Env) {
+	tok := ast.Start(node)
+	err.Path = env.Path()
+	err.Line = tok.Line
+	err.Column = tok.Column
+
+	path, line, column := err.Path, err.Line, err.Column
+	err.Stack = nil
+	for i := len(frames) - 1; i >= 0; i-- {
+		err.Stack = append(err.Stack, calleeName(frames[i].callee)+" ("+location(path, line, column)+")")
+		tok := ast.Start(frames[i].call)
+		path, line, column = frames[i].path, tok.Line, tok.Column
+	}
+	err.Stack = append(err.Stack, "[main] ("+location(path, line, column)+")")
+}
+
+func location(path string, line, column int) string {
+	if path == "" {
+		return fmt.Sprintf("%d:%d", line, column)
+	}
+	return fmt.Sprintf("%s:%d:%d", DisplayPath(path), line, column)
+}
+
+// calleeName returns the name a function value is shown by in a stack.
+func calleeName(callee object.Object) string {
+	switch callee := callee.(type) {
+	case *object.Function:
+		if callee.Name != "" {
+			return callee.Name
+		}
+	case *object.BoundMethod:
+		return callee.Fun.Name
+	case *object.Class:
+		return callee.Name
+	case *object.StructType:
+		return callee.Name
+	case *object.Std:
+		for name, std := range stds {
+			if std == callee {
+				return name
+			}
+		}
+	}
+
+	return "function"
+}
+
+func init() {
+	stds["error"] = &object.Std{
+		Signature: "error(msg, kind?)",
+		Doc:       "Returns an error with msg and kind, which throw raises.",
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+			msg, ok := args[0].(*object.Str)
+			if !ok {
+				return newError(object.TYPE_ERROR, "argument to `error` must be STR, got %s", args[0].Type())
+			}
+			kind := object.ERROR
+			if len(args) == 2 {
+				k, ok := args[1].(*object.Str)
+				if !ok || strings.TrimSpace(k.Value) == "" {
+					return newError(object.TYPE_ERROR, "kind of `error` must be a non-empty STR, got %s", args[1].Inspect())
+				}
+				kind = k.Value
+			}
+			return &object.ErrorValue{Err: &object.Error{Kind: kind, Message: msg.Value}}
+		},
+	}
+}
@@ -3,7 +3,9 @@ package eval
 import (
 	"coff-src/src/coff/object"
 	"coff-src/src/coff/ast"
+	"coff-src/src/coff/token"
 	"fmt"
+	"strings"
 )
 
 var (
@@ -13,6 +15,22 @@ var (
 )
 
 func Eval(node ast.Node, env *object.Env) object.Object {
+	if len(hooks) != 0 {
+		if _, ok := node.(*ast.Program); !ok {
+			nodeHooks(node, env)
+		}
+	}
+
+	result := evalNode(node, env)
+	// An error is raised by the innermost node it is returned from.
+	if err, ok := result.(*object.Error); ok && err.Line == 0 {
+		raised(err, node, env)
+	}
+
+	return result
+}
+
+func evalNode(node ast.Node, env *object.Env) object.Object {
 	switch node := node.(type) {
 	case *ast.Program:
 		return evalProgram(node, env)
@@ -41,6 +59,9 @@ func Eval(node ast.Node, env *object.Env) object.Object {
 		if isError(left) {
 			return left
 		}
+		if node.Token.Type == token.AND || node.Token.Type == token.OR {
+			return evalLogicalExpression(node, left, env)
+		}
 
 		right := Eval(node.Right, env)
 		if isError(right) {
@@ -55,13 +76,22 @@ func Eval(node ast.Node, env *object.Env) object.Object {
 		if isError(val) {
 			return val
 		}
+		if node.Pattern != nil {
+			if val == nil {
+				val = NULL // a nil value would count as missing to the pattern
+			}
+			if err := destructure(node.Pattern, val, env); err != nil {
+				return err
+			}
+			return nil
+		}
 		env.Set(node.Name.Value, val)
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		return allocated(&object.Function{Name: node.Name, Parameters: params, Env: env, Body: body})
 	case *ast.CallExpression:
 		function := Eval(node.Function, env)
 		if isError(function) {
@@ -72,15 +102,15 @@ func Eval(node ast.Node, env *object.Env) object.Object {
 			return args[0]
 		}
 
-		return applyFunction(function, args)
+		return evalCall(function, args, node, env)
 	case *ast.StrLiteral:
-		return &object.Str{Value: node.Value}
+		return allocated(&object.Str{Value: node.Value})
 	case *ast.ArrLiteral:
 		elements := evalExpressions(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
 			return elements[0]
 		}
-		return &object.Arr{Elements: elements}
+		return allocated(&object.Arr{Elements: elements})
 	case *ast.IdxExpression:
 		left := Eval(node.Left, env)
 		if isError(left) {
@@ -92,98 +122,301 @@ func Eval(node ast.Node, env *object.Env) object.Object {
 			return index
 		}
 		return evalIdxExpression(left, index)
+	case *ast.SliceExpression:
+		return evalSliceExpression(node, env)
+	case *ast.TupleLiteral:
+		elements := evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isError(elements[0]) {
+			return elements[0]
+		}
+		return allocated(&object.Tuple{Elements: elements})
+	case *ast.SetLiteral:
+		return evalSetLiteral(node, env)
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, env)
+	case *ast.ImportStatement:
+		return evalImportStatement(node, env)
+	case *ast.AssignStatement:
+		return evalAssignStatement(node, env)
+	case *ast.StructStatement:
+		return evalStructStatement(node, env)
+	case *ast.ClassStatement:
+		return evalClassStatement(node, env)
+	case *ast.MemberExpression:
+		return evalMemberExpression(node, env)
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
+	case *ast.ThrowStatement:
+		return evalThrowStatement(node, env)
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
 	}
 	
 	return nil
 }
 
 func evalHashLiteral(node *ast.HashLiteral, env *object.Env,) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := object.NewHash()
 	
-	for keyNode, valueNode := range node.Pairs {
+	for _, keyNode := range node.Keys {
 		key := Eval(keyNode, env)
 		if isError(key) {
 			return key
 		}
 		
-		hashKey, ok := key.(object.Hashable)
-		if !ok {
-			return newError("unusable as hash key: %s", key.Type())
+		if _, ok := object.HashKeyOf(key); !ok {
+			return newError(object.TYPE_ERROR, "unusable as hash key: %s", key.Type())
 		}
 	
-		value := Eval(valueNode, env)
+		value := Eval(node.Pairs[keyNode], env)
 		if isError(value) {
 			return value
 		}
 	
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(key, value)
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return allocated(hash)
 }
 
 func evalIdxExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARR_OBJ && index.Type() == object.INT_OBJ:
-		return evalArrIdxExpression(left, index)
+		return evalArrIdxExpression(left.(*object.Arr).Elements, index)
+	case left.Type() == object.TUPLE_OBJ && index.Type() == object.INT_OBJ:
+		return evalArrIdxExpression(left.(*object.Tuple).Elements, index)
+	case left.Type() == object.STR_OBJ && index.Type() == object.INT_OBJ:
+		return evalStrIdxExpression(left.(*object.Str).Value, index)
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIdxExpression(left, index)
 	default:
-		return newError("index operator is not supported: %s", left.Type())
+		return newError(object.TYPE_ERROR, "index operator is not supported: %s", left.Type())
 	}
 }
 
 func evalHashIdxExpression(hash, index object.Object) object.Object {
 	hashObject := hash.(*object.Hash)
 	
-	key, ok := index.(object.Hashable)
-	if !ok {
-		return newError("unusable as hash key: %s", index.Type())
+	if _, ok := object.HashKeyOf(index); !ok {
+		return newError(object.TYPE_ERROR, "unusable as hash key: %s", index.Type())
 	}
 
-	pair, ok := hashObject.Pairs[key.HashKey()]
+	value, ok := hashObject.Get(index)
 	if !ok {
 		return NULL
 	}
 	
-	return pair.Value
+	return value
 }
 
-func evalArrIdxExpression(array, index object.Object) object.Object {
-	arrayObject := array.(*object.Arr)
-	
-	idx := index.(*object.Int).Value
-	max := int64(len(arrayObject.Elements) - 1)
-	if idx < 0 || idx > max {
+// evalArrIdxExpression indexes the elements of an array or tuple. A
+// negative index counts from the end.
+func evalArrIdxExpression(elements []object.Object, index object.Object) object.Object {
+	idx, ok := position(index.(*object.Int).Value, len(elements))
+	if !ok {
 		return NULL
 	}
 	
-	return arrayObject.Elements[idx]
+	return elements[idx]
+}
+
+// evalStrIdxExpression returns the character of str at index, counting
+// in code points.
+func evalStrIdxExpression(str string, index object.Object) object.Object {
+	chars := []rune(str)
+	idx, ok := position(index.(*object.Int).Value, len(chars))
+	if !ok {
+		return NULL
+	}
+
+	return allocated(&object.Str{Value: string(chars[idx])})
+}
+
+// position returns the position index refers to in a sequence of length
+// n, counting from the end if it is negative.
+func position(index int64, n int) (int64, bool) {
+	if index < 0 {
+		index += int64(n)
+	}
+	return index, index >= 0 && index < int64(n)
+}
+
+func evalSliceExpression(node *ast.SliceExpression, env *object.Env) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	bounds := [3]*object.Int{}
+	for i, bound := range []ast.Expression{node.Low, node.High, node.Step} {
+		if bound == nil {
+			continue
+		}
+		value := Eval(bound, env)
+		if isError(value) {
+			return value
+		}
+		n, ok := value.(*object.Int)
+		if !ok {
+			return newError(object.TYPE_ERROR, "slice index must be INT, got %s", value.Type())
+		}
+		bounds[i] = n
+	}
+
+	switch left := left.(type) {
+	case *object.Arr:
+		positions, err := slicePositions(len(left.Elements), bounds)
+		if err != nil {
+			return err
+		}
+		elements := make([]object.Object, len(positions))
+		for i, pos := range positions {
+			elements[i] = left.Elements[pos]
+		}
+		return allocated(&object.Arr{Elements: elements})
+	case *object.Tuple:
+		positions, err := slicePositions(len(left.Elements), bounds)
+		if err != nil {
+			return err
+		}
+		elements := make([]object.Object, len(positions))
+		for i, pos := range positions {
+			elements[i] = left.Elements[pos]
+		}
+		return allocated(&object.Tuple{Elements: elements})
+	case *object.Str:
+		chars := []rune(left.Value)
+		positions, err := slicePositions(len(chars), bounds)
+		if err != nil {
+			return err
+		}
+		var out strings.Builder
+		for _, pos := range positions {
+			out.WriteRune(chars[pos])
+		}
+		return allocated(&object.Str{Value: out.String()})
+	default:
+		return newError(object.TYPE_ERROR, "slice operator is not supported: %s", left.Type())
+	}
+}
+
+// slicePositions returns the positions that a slice with the bounds low,
+// high and step selects from a sequence of length n. Negative bounds
+// count from the end and bounds past either end are clamped, as in
+// Python; a missing step is 1.
+func slicePositions(n int, bounds [3]*object.Int) ([]int, *object.Error) {
+	low, high, step := bounds[0], bounds[1], bounds[2]
+
+	by := int64(1)
+	if step != nil {
+		by = step.Value
+	}
+	if by == 0 {
+		return nil, newError(object.ARGUMENT_ERROR, "slice step cannot be zero")
+	}
+
+	length := int64(n)
+	clamp := func(bound *object.Int, missing, min, max int64) int64 {
+		if bound == nil {
+			return missing
+		}
+		i := bound.Value
+		if i < 0 {
+			i += length
+		}
+		if i < min {
+			return min
+		}
+		if i > max {
+			return max
+		}
+		return i
+	}
+
+	positions := []int{}
+	if by > 0 {
+		from, to := clamp(low, 0, 0, length), clamp(high, length, 0, length)
+		for i := from; i < to; i += by {
+			positions = append(positions, int(i))
+			if by >= to-i {
+				break // adding a huge step could overflow
+			}
+		}
+	} else {
+		from, to := clamp(low, length-1, -1, length-1), clamp(high, -1, -1, length-1)
+		for i := from; i > to; i += by {
+			positions = append(positions, int(i))
+			if by <= to-i {
+				break
+			}
+		}
+	}
+
+	return positions, nil
+}
+
+// Apply calls fun, a function value, with args.
+func Apply(fun object.Object, args []object.Object) object.Object {
+	return applyFunction(fun, args)
 }
 
 func applyFunction(fun object.Object, args []object.Object) object.Object {
 	switch fun := fun.(type) {
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fun, args)
-		evaluated := Eval(fun.Body, extendedEnv)
-		return unwrapRetVal(evaluated)
+		env, err := extendFunctionEnv(fun, args)
+		if err != nil {
+			return err
+		}
+		return callFunction(fun, args, env)
+	case *object.BoundMethod:
+		return applyMethod(fun, args)
+	case *object.Class:
+		return newInstance(fun, args)
 	case *object.Std:
 		return fun.Fun(args...)
+	case *object.StructType:
+		return newStruct(fun, args)
 	default:
-		return newError("not a function: %s", fun.Type())
+		return newError(object.TYPE_ERROR, "not a function: %s", fun.Type())
+	}
+}
+
+// callFunction evaluates the body of fn in env, which holds its arguments.
+func callFunction(fn *object.Function, args []object.Object, env *object.Env) object.Object {
+	if len(hooks) != 0 {
+		callHooks(fn, args, env)
 	}
+	evaluated := unwrapRetVal(Eval(fn.Body, env))
+	if len(hooks) != 0 {
+		returnHooks(fn, evaluated)
+	}
+
+	return evaluated
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object,) *object.Env {
+// extendFunctionEnv returns the environment a call of fn with args runs
+// in, with its parameters bound, or an error if args do not fit them.
+func extendFunctionEnv(fn *object.Function, args []object.Object,) (*object.Env, *object.Error) {
+	if required := ast.Required(fn.Parameters); len(args) < required {
+		name := fn.Name
+		if name == "" {
+			name = "function"
+		}
+		return nil, newError(object.ARGUMENT_ERROR, "wrong number of arguments to %s. got=%d, want=%d", name, len(args), required)
+	}
+
 	env := object.NewEnclosedEnv(fn.Env)
 	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Value, args[paramIdx])
+		var arg object.Object
+		if paramIdx < len(args) {
+			arg = args[paramIdx]
+		}
+		if err := destructure(param, arg, env); err != nil {
+			return nil, err
+		}
 	}
 	
-	return env
+	return env, nil
 }
 
 func unwrapRetVal(obj object.Object) object.Object {
@@ -216,7 +449,7 @@ func evalIdentifier(node *ast.Identifier, env *object.Env,) object.Object {
 		return std
 	}
 	
-	return newError("identifier is not found: " + node.Value)
+	return newError(object.NAME_ERROR, "identifier is not found: " + node.Value)
 }
 
 func evalBlockStatement(block *ast.BlockStatement, env *object.Env,) object.Object {
@@ -240,7 +473,12 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Env,) object.Object {
 		return condition
 	}
 
-	if isTruthy(condition) {
+	taken := isTruthy(condition)
+	if len(hooks) != 0 {
+		branchHooks(ie, taken)
+	}
+
+	if taken {
 		return Eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
 		return Eval(ie.Alternative, env)
@@ -249,6 +487,17 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Env,) object.Object {
 	}
 }
 
+// evalLogicalExpression evaluates the right operand of && or || only if
+// the left one does not decide the result, and returns the operand that
+// did.
+func evalLogicalExpression(node *ast.InfixExpression, left object.Object, env *object.Env) object.Object {
+	if isTruthy(left) == (node.Token.Type == token.OR) {
+		return left
+	}
+
+	return Eval(node.Right, env)
+}
+
 func isTruthy(obj object.Object) bool {
 	switch obj {
 	case NULL:
@@ -264,30 +513,85 @@ func isTruthy(obj object.Object) bool {
 
 func evalInfixExpression(operator string, left, right object.Object,) object.Object {
 	switch {
+	case operator == "is":
+		return evalIsExpression(left, right)
+	case operator == "in":
+		return evalInExpression(left, right)
+	case operator == "not in":
+		result := evalInExpression(left, right)
+		if isError(result) {
+			return result
+		}
+		return nativeBoolToBoolObject(result == FALSE)
 	case left.Type() == object.INT_OBJ && right.Type() == object.INT_OBJ:
 		return evalIntInfixExpression(operator, left, right)
 	case operator == "==":
-		return nativeBoolToBoolObject(left == right)
+		return nativeBoolToBoolObject(object.Equal(left, right))
 	case operator == "!=":
-		return nativeBoolToBoolObject(left != right)
-	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
-	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return nativeBoolToBoolObject(!object.Equal(left, right))
 	case left.Type() == object.STR_OBJ && right.Type() == object.STR_OBJ:
 		return evalStrInfixExpression(operator, left, right)
+	case left.Type() == object.SET_OBJ && right.Type() == object.SET_OBJ:
+		return evalSetInfixExpression(operator, left.(*object.Set), right.(*object.Set))
+	case left.Type() != right.Type():
+		return newError(object.TYPE_ERROR, "type mismatch: %s %s %s", left.Type(), operator, right.Type())
+	default:
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
-func evalStrInfixExpression(operator string, left, right object.Object,) object.Object {
-	if operator != "+" {
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+// evalInExpression reports whether right holds left: an element equal to
+// it for arrays and tuples, a key for hashes and sets and a substring for
+// strings. A value that cannot be a key is in no hash or set.
+func evalInExpression(left, right object.Object) object.Object {
+	switch right := right.(type) {
+	case *object.Arr:
+		return nativeBoolToBoolObject(contains(right.Elements, left))
+	case *object.Tuple:
+		return nativeBoolToBoolObject(contains(right.Elements, left))
+	case *object.Str:
+		sub, ok := left.(*object.Str)
+		if !ok {
+			return newError(object.TYPE_ERROR, "left operand of in must be STR when the right is, got %s", left.Type())
+		}
+		return nativeBoolToBoolObject(strings.Contains(right.Value, sub.Value))
+	case *object.Hash:
+		_, ok := right.Get(left)
+		return nativeBoolToBoolObject(ok)
+	case *object.Set:
+		return nativeBoolToBoolObject(right.Has(left))
+	default:
+		return newError(object.TYPE_ERROR, "right operand of in must be ARRAY, TUPLE, STR, HASH or SET, got %s", right.Type())
+	}
+}
+
+func contains(elements []object.Object, x object.Object) bool {
+	for _, el := range elements {
+		if object.Equal(el, x) {
+			return true
+		}
 	}
+	return false
+}
 
+func evalStrInfixExpression(operator string, left, right object.Object,) object.Object {
 	leftVal := left.(*object.Str).Value
 	rightVal := right.(*object.Str).Value
 
-	return &object.Str{Value: leftVal + rightVal}
+	switch operator {
+	case "+":
+		return allocated(&object.Str{Value: leftVal + rightVal})
+	case "<":
+		return nativeBoolToBoolObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBoolObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBoolObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBoolObject(leftVal >= rightVal)
+	default:
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
 }
 
 func evalIntInfixExpression(operator string, left, right object.Object,) object.Object {
@@ -301,34 +605,103 @@ func evalIntInfixExpression(operator string, left, right object.Object,) object.
 	case "*":
 		return &object.Int{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return newError(object.ARITHMETIC_ERROR, "division by zero")
+		}
 		return &object.Int{Value: leftVal / rightVal}
+	case "%":
+		if rightVal == 0 {
+			return newError(object.ARITHMETIC_ERROR, "division by zero")
+		}
+		return &object.Int{Value: leftVal % rightVal}
+	case "div", "mod":
+		if rightVal == 0 {
+			return newError(object.ARITHMETIC_ERROR, "division by zero")
+		}
+		q, r := floorDivide(leftVal, rightVal)
+		if operator == "div" {
+			return &object.Int{Value: q}
+		}
+		return &object.Int{Value: r}
+	case "**":
+		if rightVal < 0 {
+			return newError(object.ARITHMETIC_ERROR, "negative exponent: %d", rightVal)
+		}
+		return &object.Int{Value: power(leftVal, rightVal)}
+	case "&":
+		return &object.Int{Value: leftVal & rightVal}
+	case "|":
+		return &object.Int{Value: leftVal | rightVal}
+	case "^":
+		return &object.Int{Value: leftVal ^ rightVal}
+	case "<<", ">>":
+		if rightVal < 0 {
+			return newError(object.ARITHMETIC_ERROR, "negative shift count: %d", rightVal)
+		}
+		if operator == "<<" {
+			return &object.Int{Value: leftVal << uint64(rightVal)}
+		}
+		return &object.Int{Value: leftVal >> uint64(rightVal)}
 	case "<":
 		return nativeBoolToBoolObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBoolObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBoolObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBoolObject(leftVal >= rightVal)
 	case "==":
 		return nativeBoolToBoolObject(leftVal == rightVal)
 	case "!=":
 		return nativeBoolToBoolObject(leftVal != rightVal)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// floorDivide divides a by b rounding towards negative infinity, so that
+// the remainder has the sign of b and a == q*b + r.
+func floorDivide(a, b int64) (q, r int64) {
+	q, r = a/b, a%b
+	if r != 0 && (r < 0) != (b < 0) {
+		q--
+		r += b
+	}
+	return q, r
+}
+
+// power returns base raised to exp, which must not be negative.
+func power(base, exp int64) int64 {
+	result := int64(1)
+	for exp > 0 {
+		if exp&1 == 1 {
+			result *= base
+		}
+		base *= base
+		exp >>= 1
 	}
+	return result
 }
 
 func evalPrefixExpression(operator string, right object.Object) object.Object {
 	switch operator {
-	case "!":
+	case "!", "not":
 		return evalFacOperatorExpression(right)
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
+	case "~":
+		if right.Type() != object.INT_OBJ {
+			return newError(object.TYPE_ERROR, "unknown operator: ~%s", right.Type())
+		}
+		return &object.Int{Value: ^right.(*object.Int).Value}
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newError(object.TYPE_ERROR, "unknown operator: %s%s", operator, right.Type())
 	}
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
 	if right.Type() != object.INT_OBJ {
-		return newError("unknown operator: -%s", right.Type())
+		return newError(object.TYPE_ERROR, "unknown operator: -%s", right.Type())
 	}
 
 	value := right.(*object.Int).Value
@@ -373,8 +746,8 @@ func nativeBoolToBoolObject(input bool) *object.Bool {
 	return FALSE
 }
 
-func newError(format string, a ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, a...)}
+func newError(kind string, format string, a ...interface{}) *object.Error {
+	return &object.Error{Kind: kind, Message: fmt.Sprintf(format, a...)}
 }
 
 func isError(obj object.Object) bool {
@@ -1,9 +1,13 @@
 package eval
 
 import (
+	"bytes"
+	"coff-src/src/coff/ast"
 	"coff-src/src/coff/parser"
 	"coff-src/src/coff/object"
 	"coff-src/src/coff/lexer"
+	"os"
+	"strings"
 	"testing"
 )
 
@@ -71,25 +75,53 @@ func TestHashLiterals(t *testing.T) {
 		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
 	}
 
-	expected := map[object.HashKey]int64{
-		(&object.Str{Value: "one"}).HashKey(): 1,
-		(&object.Str{Value: "two"}).HashKey(): 2,
-		(&object.Str{Value: "three"}).HashKey(): 3,
-		(&object.Int{Value: 4}).HashKey(): 4,
-		TRUE.HashKey(): 5,
-		FALSE.HashKey(): 6,
+	expected := []struct {
+		key   object.Object
+		value int64
+	}{
+		{&object.Str{Value: "one"}, 1},
+		{&object.Str{Value: "two"}, 2},
+		{&object.Str{Value: "three"}, 3},
+		{&object.Int{Value: 4}, 4},
+		{TRUE, 5},
+		{FALSE, 6},
 	}
 
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	if result.Len() != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", result.Len())
 	}
 
-	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
+	for i, pair := range result.Pairs() {
+		if !object.Equal(pair.Key, expected[i].key) {
+			t.Errorf("pair %d has wrong key. expected=%s, got=%s", i, expected[i].key.Inspect(), pair.Key.Inspect())
+		}
+		testIntObject(t, pair.Value, expected[i].value)
+	}
+
+	for _, e := range expected {
+		value, ok := result.Get(e.key)
 		if !ok {
-			t.Errorf("no pair for given key in Pairs")
+			t.Errorf("no pair for key %s", e.key.Inspect())
+			continue
+		}
+		testIntObject(t, value, e.value)
+	}
+}
+
+func TestHashInspectOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"b": 1, "a": 2, 3: 3}`, "{b: 1, a: 2, 3: 3}"},
+		{`{"b": 1, "a": 2, "b": 3}`, "{b: 3, a: 2}"},
+		{`def h = {"z": 1}; h.y = 2; h.z = 3; h`, "{z: 3, y: 2}"},
+	}
+
+	for _, tt := range tests {
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("%s: wrong inspect. expected=%q, got=%q", tt.input, tt.expected, got)
 		}
-		testIntObject(t, pair.Value, expectedValue)
 	}
 }
 
@@ -113,6 +145,32 @@ func TestEvalIntExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"7 / 2", 3},
+		{"-7 / 2", -3},
+		{"7 / -2", -3},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"7 % -3", 1},
+		{"7 div 2", 3},
+		{"-7 div 2", -4},
+		{"7 div -2", -4},
+		{"-7 mod 3", 2},
+		{"7 mod -3", -2},
+		{"6 mod -3", 0},
+		{"1 + 7 div 2 * 2", 7},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"(-2) ** 3", -8},
+		{"5 ** 0", 1},
+		{"6 & 3", 2},
+		{"6 | 3", 7},
+		{"6 ^ 3", 5},
+		{"~5", -6},
+		{"1 << 4", 16},
+		{"-16 >> 2", -4},
+		{"1 + 2 << 1", 6},
+		{"1 | 2 ^ 3 & 4", 3},
 	}
 
 	for _, tt := range tests {
@@ -199,6 +257,42 @@ func TestErrorHandling(t *testing.T) {
 			`{"name": "Monkey"}[fun(x) { x }];`,
 			"unusable as hash key: FUN",
 		},
+		{
+			"1 / 0",
+			"division by zero",
+		},
+		{
+			"1 % 0",
+			"division by zero",
+		},
+		{
+			"1 div 0",
+			"division by zero",
+		},
+		{
+			"1 mod 0",
+			"division by zero",
+		},
+		{
+			`1 div "a"`,
+			"type mismatch: INT div STR",
+		},
+		{
+			"2 ** -1",
+			"negative exponent: -1",
+		},
+		{
+			"1 << -1",
+			"negative shift count: -1",
+		},
+		{
+			"~true",
+			"unknown operator: ~BOOL",
+		},
+		{
+			`"a" * "b"`,
+			"unknown operator: STR * STR",
+		},
 	}
 		
 	for _, tt := range tests {
@@ -333,6 +427,31 @@ func TestEvalBoolExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{"1 <= 1", true},
+		{"2 <= 1", false},
+		{"1 >= 1", true},
+		{"1 >= 2", false},
+		{`"a" < "b"`, true},
+		{`"b" < "a"`, false},
+		{`"ab" > "a"`, true},
+		{`"a" <= "a"`, true},
+		{`"B" >= "a"`, false},
+		{`"a" == "a"`, true},
+		{`"a" != "a"`, false},
+		{`"a" == "b"`, false},
+		{"[1, [2, 3]] == [1, [2, 3]]", true},
+		{"[1, 2] == [1, 3]", false},
+		{"[1, 2] != [1, 2, 3]", true},
+		{"[] == []", true},
+		{`{"a": [1], "b": 2} == {"b": 2, "a": [1]}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} == {"b": 1}`, false},
+		{"[1] == 1", false},
+		{`1 == "1"`, false},
+		{"def f = fun() { 1 }; f == f", true},
+		{"fun() { 1 } == fun() { 1 }", false},
+		{`def a = {"x": 1}; def b = {"x": 1}; a.self = a; b.self = b; a == b`, true},
+		{`def a = {"x": 1}; def b = {"x": 2}; a.self = a; b.self = b; a == b`, false},
 	}
 
 	for _, tt := range tests {
@@ -375,6 +494,52 @@ func TestFacOperator(t *testing.T) {
 	}
 }
 
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"true && false", false},
+		{"true && true", true},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 && 2", 2},
+		{"0 && 2", 2},
+		{"false && 2", false},
+		{"1 || 2", 1},
+		{"false || 2", 2},
+		{"true and 1 < 2", true},
+		{"false or 3", 3},
+		{"not true", false},
+		{"not 1 == 2", true},
+		{"not false and false", false},
+		{"false && x", false},
+		{"true || x", true},
+		{"def f = fun(n) { n > 0 && f(n - 1) || n == 0 }; f(3)", true},
+		{"true && x", "identifier is not found: x"},
+		{"x || true", "identifier is not found: x"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntObject(t, evaluated, int64(expected))
+		case bool:
+			testBoolObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%s: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
 func TestDefStatements(t *testing.T) {
 	tests := []struct {
 		input string
@@ -468,6 +633,8 @@ func TestStdFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
+		{`len("naïve")`, 5},
+		{`len("☕")`, 1},
 		{`len(1)`, "argument to `len` is not supported, got INT"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
 	}
@@ -552,8 +719,20 @@ func TestArrIdxExpressions(t *testing.T) {
 		},
 		{
 			"[1, 2, 3][-1]",
+			3,
+		},
+		{
+			"[1, 2, 3][-3]",
+			1,
+		},
+		{
+			"[1, 2, 3][-4]",
 			nil,
 		},
+		{
+			"(1, 2)[-1]",
+			2,
+		},
 	}
 
 	for _, tt := range tests {
@@ -565,4 +744,772 @@ func TestArrIdxExpressions(t *testing.T) {
 			testNullObject(t, evaluated)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // the Inspect of the result
+	}{
+		{"[1, 2, 3, 4][1:3]", "[2, 3]"},
+		{"[1, 2, 3, 4][:2]", "[1, 2]"},
+		{"[1, 2, 3, 4][2:]", "[3, 4]"},
+		{"[1, 2, 3, 4][:]", "[1, 2, 3, 4]"},
+		{"[1, 2, 3, 4][-2:]", "[3, 4]"},
+		{"[1, 2, 3, 4][:-1]", "[1, 2, 3]"},
+		{"[1, 2, 3, 4][-10:10]", "[1, 2, 3, 4]"},
+		{"[1, 2, 3, 4][3:1]", "[]"},
+		{"[1, 2, 3, 4][::2]", "[1, 3]"},
+		{"[1, 2, 3, 4][1::2]", "[2, 4]"},
+		{"[1, 2, 3, 4][::-1]", "[4, 3, 2, 1]"},
+		{"[1, 2, 3, 4][2::-1]", "[3, 2, 1]"},
+		{"[1, 2, 3, 4][:0:-2]", "[4, 2]"},
+		{"[1, 2, 3, 4][-1:-3:-1]", "[4, 3]"},
+		{"[1, 2, 3][1::9223372036854775807]", "[2]"},
+		{"[1, 2, 3][::-9223372036854775807]", "[3]"},
+		{"[1, 2, 3][::-9223372036854775807 - 1]", "[3]"},
+		{`"abc"[::9223372036854775807]`, "a"},
+		{"[][1:]", "[]"},
+		{"(1, 2, 3)[1:]", "(2, 3)"},
+		{`"hello"[1:4]`, "ell"},
+		{`"hello"[-3:]`, "llo"},
+		{`"hello"[::-1]`, "olleh"},
+		{`"hello"[1]`, "e"},
+		{`"hello"[-1]`, "o"},
+		{`"hello"[5]`, "null"},
+		{"def a = [1, 2, 3]; def i = 1; a[i:i + 1]", "[2]"},
+		{"[1, 2][::0]", "ERROR: slice step cannot be zero"},
+		{`[1, 2]["a":]`, "ERROR: slice index must be INT, got STR"},
+		{"5[1:]", "ERROR: slice operator is not supported: INT"},
+	}
+
+	for _, tt := range tests {
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("%s: wrong result. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestUnicodeStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // the Inspect of the result
+	}{
+		{`def café = "ok"; café`, "ok"},
+		{`"naïve"[2]`, "ï"},
+		{`"naïve"[-1]`, "e"},
+		{`"naïve"[1:4]`, "aïv"},
+		{`"añb☕"[::-1]`, "☕bña"},
+		{`array("añ☕")`, "[a, ñ, ☕]"},
+		{`"ï" in "naïve"`, "true"},
+		{`bytes("añ")`, "[97, 195, 177]"},
+		{`runes("añ☕")`, "[97, 241, 9749]"},
+		{`bytes("")`, "[]"},
+		{`ord("☕")`, "9749"},
+		{`ord("a")`, "97"},
+		{`chr(241)`, "ñ"},
+		{`chr(ord("z"))`, "z"},
+		{`ord("ab")`, "ERROR: argument to `ord` must be a single character, got 2 characters"},
+		{`ord(1)`, "ERROR: argument to `ord` must be STR, got INT"},
+		{`chr(-1)`, "ERROR: invalid code point: -1"},
+		{`chr(55296)`, "ERROR: invalid code point: 55296"},
+		{`chr("a")`, "ERROR: argument to `chr` must be INT, got STR"},
+		{`runes(1)`, "ERROR: argument to `runes` must be STR, got INT"},
+		{`bytes([])`, "ERROR: argument to `bytes` must be STR, got ARR"},
+	}
+
+	for _, tt := range tests {
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("%s: wrong result. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // the failure message, empty if the assertion holds
+	}{
+		{`assert(1 < 2)`, ""},
+		{`assert(1 > 2)`, "assertion failed"},
+		{`assert(false, "not true")`, "not true"},
+		{`assertEq(1 + 1, 2)`, ""},
+		{`assertEq([1, [2, 3]], [1, [2, 3]])`, ""},
+		{`assertEq({"a": [1], "b": 2}, {"b": 2, "a": [1]})`, ""},
+		{`assertEq([1, 2], [1, 3])`, "expected [1, 3], got [1, 2]"},
+		{`assertEq({"a": 1}, {"b": 1})`, "expected {b: 1}, got {a: 1}"},
+		{`assertEq("1", 1)`, "expected 1, got 1"},
+		{`assertEq(1, 2, "sum")`, "sum"},
+		{`def f = fun() { 1 }; assertEq(f, f)`, ""},
+		{`assertError(fun() { 1 + true })`, ""},
+		{`assertError(fun() { 1 + true }, "type mismatch")`, ""},
+		{`assertError(fun() { 1 + true }, "unknown")`, `expected an error containing "unknown", got "type mismatch: INT + BOOL"`},
+		{`assertError(fun() { 1 })`, "expected an error, got 1"},
+		{`assertError(fun() { def x = 1; })`, "expected an error, got null"},
+		{`assertError(fun(a) { 1 + true })`, "argument to `assertError` must take no arguments, got 1 required parameters"},
+		{`assertError(fun(a = 1) { 1 + true })`, ""},
+		{`class A { check(n) { 1 + true } }; assertError(A().check)`, "argument to `assertError` must take no arguments, got 1 required parameters"},
+		{`class A { check() { 1 + true } }; assertError(A().check, "type mismatch")`, ""},
+		{`class A { init(n) {} }; assertError(A)`, "argument to `assertError` must take no arguments, got 1 required parameters"},
+		{`struct P { x }; assertError(P)`, "argument to `assertError` must take no arguments, got 1 required parameters"},
+		{`assertError(1)`, "argument to `assertError` must be callable, got INT"},
+		{`fail()`, "failed"},
+		{`fail("not done")`, "not done"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if tt.expected == "" {
+			testNullObject(t, evaluated)
+			continue
+		}
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("%s: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		}
+	}
+}
+
+func testEvalFile(path string, input string) object.Object {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	env := object.NewEnv()
+	env.SetPath(path)
+
+	return Eval(program, env)
+}
+
+func TestImports(t *testing.T) {
+	SearchPath = []string{"testdata/search"}
+	defer func() { SearchPath = nil }()
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`import "math.coff" as math; math.square(3)`, 9},
+		{`import "math.coff" as m; m.answer + 1`, 43},
+		{`import "math.coff" as m; m.Vec(1, 2).y`, 2},
+		{`import "../modules/math.coff" as math; math.answer`, 42},
+		{`import "util.coff" as util; util.cube(2)`, 8},
+		{`import "math.coff" as math; math.helper`, "module testdata/modules/math.coff has no exported member helper"},
+		{`import "missing.coff" as m; 1`, `module not found: "missing.coff"`},
+		{`import "cycle_a.coff" as a; 1`, "import cycle: testdata/modules/cycle_a.coff -> testdata/modules/cycle_b.coff -> testdata/modules/cycle_a.coff"},
+		{`def x = 1; x.y`, "member access is not supported: INT"},
+		{`import "math.coff" as math; math.answer = 1`, "cannot assign to member answer of module testdata/modules/math.coff"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalFile("testdata/modules/main.coff", tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%s: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestModuleEvaluatedOnce(t *testing.T) {
+	modules = map[string]*object.Module{}
+
+	var out bytes.Buffer
+	Stdout = &out
+	defer func() { Stdout = os.Stdout }()
+
+	input := `import "loud.coff" as a; import "./loud.coff" as b; a == b`
+	testBoolObject(t, testEvalFile("testdata/modules/main.coff", input), true)
+	testEvalFile("testdata/modules/other.coff", `import "loud.coff" as c;`)
+
+	if out.String() != "loading loud.coff\n" {
+		t.Errorf("module not evaluated exactly once, printed %q", out.String())
+	}
+}
+
+func TestPrintCyclicValues(t *testing.T) {
+	tests := map[string]string{
+		`def h = {"a": 1}; h.self = h; print(h);`:             "{a: 1, self: {...}}\n",
+		`def h = {}; h.l = [h, (h,)]; print(h.l);`:            "[{l: [...]}, ({l: [...]},)]\n",
+		`def a = {}; def b = {"a": a}; a.b = b; print(a, b);`: "{b: {a: {...}}}\n{a: {b: {...}}}\n",
+		`def h = {"x": 1}; print([h, h]);`:                    "[{x: 1}, {x: 1}]\n",
+		`class A { init() { self.me = self; } }; print(A());`: "A{me: A{...}}\n",
+		`class N { init(n) { self.n = n; self.all = [self]; } }; print(N(1));`: "N{n: 1, all: [N{...}]}\n",
+	}
+
+	for input, expected := range tests {
+		var out bytes.Buffer
+		Stdout = &out
+		testEval(input)
+		Stdout = os.Stdout
+
+		if out.String() != expected {
+			t.Errorf("%s: wrong output. expected=%q, got=%q", input, expected, out.String())
+		}
+	}
+}
+
+func TestImportAfterAbandonedImport(t *testing.T) {
+	modules = map[string]*object.Module{}
+	Stdout = &bytes.Buffer{}
+	defer func() { Stdout = os.Stdout }()
+
+	abandon := &struct{}{}
+	remove := AddHooks(&Hooks{Node: func(node ast.Node, env *object.Env) {
+		if strings.HasSuffix(env.Path(), "loud.coff") {
+			panic(abandon)
+		}
+	}})
+	func() {
+		defer func() {
+			if r := recover(); r != abandon {
+				panic(r)
+			}
+		}()
+		testEvalFile("testdata/modules/main.coff", `import "loud.coff" as a;`)
+	}()
+	remove()
+
+	result := testEvalFile("testdata/modules/main.coff", `import "loud.coff" as a; a.volume`)
+	testIntObject(t, result, 11)
+}
+
+func TestHashMembers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`def user = {"name": "ann", "age": 30}; user.age`, 30},
+		{`def user = {"address": {"zip": 1234}}; user.address.zip`, 1234},
+		{`def obj = {"double": fun(x) { x * 2 }}; obj.double(21)`, 42},
+		{`def h = {}; h.count = 1; h.count = h.count + 1; h["count"]`, 2},
+		{`def h = {"inner": {}}; def alias = h.inner; alias.n = 7; h.inner.n`, 7},
+		{`def h = {"name": "ann"}; h.age`, "hash has no member age"},
+		{`def h = {}; h.a.b = 1`, "hash has no member a"},
+		{`def x = 1; x.y = 2`, "member assignment is not supported: INT"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%s: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestStructs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"struct Point { x, y }; Point(1, 2).y", 2},
+		{"struct Point { x, y }; def p = Point(1, 2); p.x = p.x + 10; p.x", 11},
+		{"struct Point { x, y }; Point(1, 2) == Point(1, 2)", true},
+		{"struct Point { x, y }; Point(1, 2) == Point(2, 1)", false},
+		{"struct Point { x, y }; Point(1, 2) != Point(2, 1)", true},
+		{"struct Point { x, y }; struct Pair { x, y }; Point(1, 2) == Pair(1, 2)", false},
+		{"struct Line { from, to }; struct Point { x, y }; Line(Point(0, 0), [1]) == Line(Point(0, 0), [1])", true},
+		{"struct Point { x, y }; Point(1, 2).z", "Point has no field z"},
+		{"struct Point { x, y }; def p = Point(1, 2); p.z = 3", "Point has no field z"},
+		{"struct Point { x, y }; Point(1)", "wrong number of arguments to Point. got=1, want=2"},
+		{"struct Point { x, y }; Point(1, 2) + Point(1, 2)", "unknown operator: STRUCT + STRUCT"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntObject(t, evaluated, int64(expected))
+		case bool:
+			testBoolObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%s: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestStructInspect(t *testing.T) {
+	evaluated := testEval(`struct User { name, tags }; User("ann", [1, 2])`)
+	if evaluated.Inspect() != "User{name: ann, tags: [1, 2]}" {
+		t.Errorf("wrong Inspect. got=%q", evaluated.Inspect())
+	}
+
+	evaluated = testEval(`struct User { name }; User`)
+	if evaluated.Inspect() != "<struct User>" {
+		t.Errorf("wrong Inspect. got=%q", evaluated.Inspect())
+	}
+}
+
+func TestClasses(t *testing.T) {
+	classes := `
+class Animal {
+	init(name) { self.name = name; }
+	speak() { self.name + " makes a sound" }
+	rename(name) { self.name = name; self }
+}
+class Dog < Animal {
+	init(name) { super.init(name); self.tricks = 0; }
+	speak() { super.speak() + " and barks" }
+	learn() { self.tricks = self.tricks + 1; self }
+}
+class Puppy < Dog {}
+`
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`Animal("cat").speak()`, "cat makes a sound"},
+		{`Dog("rex").speak()`, "rex makes a sound and barks"},
+		{`Puppy("bit").speak()`, "bit makes a sound and barks"},
+		{`Dog("rex").learn().learn().tricks`, 2},
+		{`Dog("rex").rename("max").speak()`, "max makes a sound and barks"},
+		{`def speak = Dog("rex").speak; speak()`, "rex makes a sound and barks"},
+		{`def d = Dog("rex"); d.name = "max"; d.speak()`, "max makes a sound and barks"},
+		{`Dog("rex") is Dog`, true},
+		{`Puppy("bit") is Animal`, true},
+		{`Animal("cat") is Dog`, false},
+		{`1 is Animal`, false},
+		{`def d = Dog("rex"); d == d`, true},
+		{`Dog("rex") == Dog("rex")`, false},
+		{`Dog("rex").fly()`, "Dog has no member fly"},
+		{`Dog()`, "wrong number of arguments to Dog.init. got=0, want=1"},
+		{`class A {}; A(1)`, "wrong number of arguments to A. got=1, want=0"},
+		{`class A { f() { super.f() } }; A().f()`, "identifier is not found: super"},
+		{`class B < Animal { f() { super.fly() } }; B("b").f()`, "Animal has no method fly"},
+		{`def x = 1; class A < x {}`, "A cannot extend INT: not a class"},
+		{`1 is 2`, "right operand of is must be a class or struct, got INT"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(classes + tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntObject(t, evaluated, int64(expected))
+		case bool:
+			testBoolObject(t, evaluated, expected)
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.Str:
+				if obj.Value != expected {
+					t.Errorf("%s: wrong string. expected=%q, got=%q", tt.input, expected, obj.Value)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, obj.Message)
+				}
+			default:
+				t.Errorf("%s: unexpected object %T (%+v)", tt.input, evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestInstanceInspect(t *testing.T) {
+	input := `class P { init() { self.y = 2; self.x = 1; self.y = 3; } }; P()`
+	if got := testEval(input).Inspect(); got != "P{y: 3, x: 1}" {
+		t.Errorf("wrong Inspect. got=%q", got)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`match 2 { 1 => 10, 2 => 20, _ => 30 }`, 20},
+		{`match 5 { 1 => 10, _ => 30 }`, 30},
+		{`match -1 { -1 => 1, _ => 2 }`, 1},
+		{`match "b" { "a" => 1, "b" => 2 }`, 2},
+		{`match false { true => 1, false => 2 }`, 2},
+		{`match 7 { n => n * 2 }`, 14},
+		{`match [1, 2, 3] { [a, b] => 0, [a, b, c] => a + b + c }`, 6},
+		{`match [1, 2, 3] { [head, ...tail] => head + len(tail) }`, 3},
+		{`match [1] { [head, ...tail] => len(tail) }`, 0},
+		{`match [] { [head, ...tail] => 1, [] => 2 }`, 2},
+		{`match [[1, 2], 3] { [[a, b], c] => a + b + c }`, 6},
+		{`match {"type": "user", "id": 4, "x": 0} { {"type": "admin"} => 0, {"type": "user", "id": id} => id }`, 4},
+		{`match {"id": 4} { {"type": _} => 0, _ => 1 }`, 1},
+		{`match 1 { {"id": id} => id, [x] => x, _ => 9 }`, 9},
+		{`match 15 { n if n > 10 => 1, n => 2 }`, 1},
+		{`match 5 { n if n > 10 => 1, n => 2 }`, 2},
+		{`def n = 1; match 2 { n => n }; n`, 1},
+		{`match 3 { 1 => 0 }`, "no pattern matches 3"},
+		{`match [1] { [] => 0 }`, "no pattern matches [1]"},
+		{"def f = fun() { def y = 1; }; match f() { 1 => 0 }", "no pattern matches null"},
+		{"def f = fun() { def y = 1; }; match f() { 1 => 1, _ => 0 }", 0},
+		{`match 1 { n if m => 0 }`, "identifier is not found: m"},
+		{`match x { _ => 0 }`, "identifier is not found: x"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%s: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestDestructuring(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"def [a, b] = [1, 2]; a * 10 + b", 12},
+		{"def [a, ...rest] = [1, 2, 3]; len(rest)", 2},
+		{"def [a, ...rest] = [1]; len(rest)", 0},
+		{"def [a, [b, c]] = [1, [2, 3]]; a + b + c", 6},
+		{"def [a, b = 5] = [1]; a + b", 6},
+		{"def [a, b = a + 1] = [1]; b", 2},
+		{"def [_, b] = [1, 2]; b", 2},
+		{`def {name, age} = {"name": "ann", "age": 30}; age`, 30},
+		{`def {"user": {id}} = {"user": {"id": 7}}; id`, 7},
+		{`def {age = 18} = {}; age`, 18},
+		{`struct Point { x, y }; def {x, y} = Point(3, 4); x * y`, 12},
+		{`class P { init() { self.v = 5; } }; def {v} = P(); v`, 5},
+		{"def f = fun([a, b]) { a + b }; f([1, 2])", 3},
+		{`def f = fun({x, y = 10}) { x + y }; f({"x": 1})`, 11},
+		{"def f = fun(a, b = 2) { a * b }; f(3)", 6},
+		{"def f = fun(a, b = 2) { a * b }; f(3, 3)", 9},
+		{"def f = fun(a, b = a) { a * b }; f(4)", 16},
+		{"def [a, b] = [1]", "[1] does not match [a, b]"},
+		{"def [a] = [1, 2]", "[1, 2] does not match [a]"},
+		{"def {name} = {}", "{} does not match {name}"},
+		{"def [a] = 1", "1 does not match [a]"},
+		{"def f = fun() { def y = 1; }; def [a] = f();", "null does not match [a]"},
+		{"def f = fun() { def y = 1; }; def [a = 1] = f();", "null does not match [a = 1]"},
+		{"def f = fun([a, b]) { a }; f([1])", "[1] does not match [a, b]"},
+		{"def f = fun(a, b = 1) { a }; f()", "wrong number of arguments to f. got=0, want=1"},
+		{"def f = fun(a, b = c) { a }; f(1)", "identifier is not found: c"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%s: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestExceptions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"try { 1 } catch (e) { 2 }", 1},
+		{`try { throw "x"; 1 } catch (e) { 2 }`, 2},
+		{`try { throw "x" } catch { 7 }`, 7},
+		{`try { throw "x" } catch (e) { match e.message { "x" => true, _ => false } }`, true},
+		{`try { throw "x" } catch (e) { match e.kind { "Error" => true, _ => false } }`, true},
+		{"try { throw 42 } catch (e) { e.value }", 42},
+		{`try { throw error("bad", "ValueError") } catch (e) { match e.kind { "ValueError" => true, _ => false } }`, true},
+		{`try { 1 + true } catch (e) { match e.kind { "TypeError" => true, _ => false } }`, true},
+		{`try { len(1) } catch (e) { match e.kind { "TypeError" => true, _ => false } }`, true},
+		{`try { len(1, 2) } catch (e) { match e.kind { "ArgumentError" => true, _ => false } }`, true},
+		{`try { x } catch (e) { match e.kind { "NameError" => true, _ => false } }`, true},
+		{`try { {}.a } catch (e) { match e.kind { "MemberError" => true, _ => false } }`, true},
+		{`try { match 1 { 2 => 0 } } catch (e) { match e.kind { "MatchError" => true, _ => false } }`, true},
+		{`try { assert(false) } catch (e) { match e.kind { "AssertionError" => true, _ => false } }`, true},
+		{`try { try { throw "a" } catch (e) { throw e } } catch (e) { match e.message { "a" => true, _ => false } }`, true},
+		{`def s = {"n": 0}; try { s.n = 1; throw "x" } catch (e) { s.n = s.n * 10 } finally { s.n = s.n + 2 }; s.n`, 12},
+		{`def s = {"n": 0}; try { 5 } finally { s.n = 1 }; s.n`, 1},
+		{"def v = try { 1 } finally { 2 }; v", 1},
+		{"def f = fun() { try { ret 1 } finally { 2 }; 3 }; f()", 1},
+		{`throw "boom"`, "boom"},
+		{`try { throw "x" } finally { 1 }`, "x"},
+		{`try { throw "a" } catch (e) { 1 } finally { throw "b" }`, "b"},
+		{`try { throw "x" } catch (e) { 1 }; e`, "identifier is not found: e"},
+		{`try { throw "x" } catch (e) { e.foo }`, "error has no member foo"},
+		{`throw error(1)`, "argument to `error` must be STR, got INT"},
+		{`def e = error("bad"); try { throw e } catch (x) { 0 }; try { 1; throw e } catch (x) { x.column }`, 65},
+		{`try { try { throw "a" } catch (e) {
+			throw e } } catch (e) { e.line }`, 2},
+		{`try { try { throw "a" } catch (e) { throw e } } catch (e) { e.value == "a" }`, true},
+		{`try { throw "x" } catch (e) { e.message == "x" }`, true},
+		{`try { throw "x" } catch (e) { e.kind == "Error" }`, true},
+		{`try { 1 + true } catch (e) { e.kind == "TypeError" }`, true},
+		{`try { len(1) } catch (e) { e.kind == "TypeError" }`, true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntObject(t, evaluated, int64(expected))
+		case bool:
+			testBoolObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%s: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestErrorPosition(t *testing.T) {
+	input := `def f = fun(x) {
+  ret x + true;
+};
+def r = try { f(1) } catch (e) { e };
+r`
+
+	evaluated := testEval(input)
+	ev, ok := evaluated.(*object.ErrorValue)
+	if !ok {
+		t.Fatalf("object is not ErrorValue. got=%T (%+v)", evaluated, evaluated)
+	}
+	err := ev.Err
+	if err.Kind != object.TYPE_ERROR {
+		t.Errorf("wrong kind. got=%q", err.Kind)
+	}
+	if err.Line != 2 || err.Column != 7 {
+		t.Errorf("wrong position. expected 2:7, got %d:%d", err.Line, err.Column)
+	}
+	stack := []string{"f (2:7)", "[main] (4:15)"}
+	if strings.Join(err.Stack, "|") != strings.Join(stack, "|") {
+		t.Errorf("wrong stack. expected=%q, got=%q", stack, err.Stack)
+	}
+	if ev.Inspect() != "TypeError: type mismatch: INT + BOOL" {
+		t.Errorf("wrong inspect. got=%q", ev.Inspect())
+	}
+
+	uncaught, ok := testEval("\n  throw 1;").(*object.Error)
+	if !ok || uncaught.Line != 2 || uncaught.Column != 3 || len(uncaught.Stack) != 1 {
+		t.Errorf("wrong uncaught error: %+v", uncaught)
+	}
+}
+
+func TestTuples(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"(1, 2)[1]", 2},
+		{"(1, 2)[2]", nil},
+		{"len((1, 2, 3))", 3},
+		{"len(())", 0},
+		{"len((1,))", 1},
+		{"(1)", 1},
+		{"(1, [2]) == (1, [2])", true},
+		{"(1, 2) == [1, 2]", false},
+		{`def grid = {(0, 0): "a", (0, 1): "b"}; grid[(0, 1)] == "b"`, true},
+		{`def grid = {(0, 0): "a"}; grid[(1, 0)]`, nil},
+		{`{((1, 2), "k"): 5}[((1, 2), "k")]`, 5},
+		{`{(1, 2): 5}[tuple([1, 2])]`, 5},
+		{`def memo = {}; memo.x = 1; {(): 3}[()]`, 3},
+		{"def [x, y] = (3, 4); x * y", 12},
+		{"match (1, 2, 3) { [a, ...rest] => len(rest) }", 2},
+		{"{[1]: 2}", "unusable as hash key: ARR"},
+		{"{(1, [2]): 2}", "unusable as hash key: TUPLE"},
+		{"{}[(1, [2])]", "unusable as hash key: TUPLE"},
+		{"tuple(1)", "argument to `tuple` must be ARRAY or SET, got INT"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntObject(t, evaluated, int64(expected))
+		case bool:
+			testBoolObject(t, evaluated, expected)
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%s: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestSets(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"len(#{1, 2, 2, 3})", 3},
+		{"len(#{})", 0},
+		{"2 in #{1, 2}", true},
+		{"5 in #{1, 2}", false},
+		{`"a" in #{"a"}`, true},
+		{"(1, 2) in #{(1, 2)}", true},
+		{"[1] in #{1}", false},
+		{"#{1, 2} == #{2, 1}", true},
+		{"#{1, 2} != #{1}", true},
+		{"#{1} == [1]", false},
+		{"len(#{1, 2} | #{2, 3})", 3},
+		{"len(#{1, 2} & #{2, 3})", 1},
+		{"#{1, 2} - #{2, 3} == #{1}", true},
+		{"#{1, 2} ^ #{2, 3} == #{1, 3}", true},
+		{"def s = #{1}; def t = s.add(2); len(s) * 10 + len(t)", 12},
+		{"def s = #{1, 2}; 1 in s.remove(1)", false},
+		{"len(#{1}.remove(5))", 1},
+		{"len(set([1, 1, 2]))", 2},
+		{"first(array(#{3, 1, 3}))", 3},
+		{"len(array((1, 2)))", 2},
+		{"set(tuple(#{1, 2})) == #{1, 2}", true},
+		{"#{[1]}", "unusable as set element: ARR"},
+		{"#{1}.add({})", "unusable as set element: HASH"},
+		{"set([[1]])", "unusable as set element: ARR"},
+		{"set(1)", "argument to `set` must be ARRAY or TUPLE, got INT"},
+		{"array(1)", "argument to `array` must be TUPLE, SET or STR, got INT"},
+		{"#{1}.pop", "set has no member pop"},
+		{"1 in 1", "right operand of in must be ARRAY, TUPLE, STR, HASH or SET, got INT"},
+		{"#{1} + #{2}", "unknown operator: SET + SET"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntObject(t, evaluated, int64(expected))
+		case bool:
+			testBoolObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%s: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestInExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"2 in [1, 2, 3]", true},
+		{"4 in [1, 2, 3]", false},
+		{"[1, [2]] in [[1, [2]], 3]", true},
+		{`"b" in ["a", "b"]`, true},
+		{"2 in (1, 2)", true},
+		{`"ell" in "hello"`, true},
+		{`"" in "hello"`, true},
+		{`"hey" in "hello"`, false},
+		{`"a" in {"a": 1}`, true},
+		{`1 in {"a": 1}`, false},
+		{"(1, 2) in {(1, 2): 0}", true},
+		{"[1] in {}", false},
+		{"3 not in [1, 2]", true},
+		{`"a" not in {"a": false}`, false},
+		{"1 not in #{1}", false},
+		{"1 in [1] == true", true},
+		{"not 1 in [2]", true},
+		{"1 + 1 in [2]", true},
+		{"def xs = [1, 2]; if (2 in xs) { 10 } else { 20 }", 10},
+		{`1 in "abc"`, "left operand of in must be STR when the right is, got INT"},
+		{"1 not in 2", "right operand of in must be ARRAY, TUPLE, STR, HASH or SET, got INT"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntObject(t, evaluated, int64(expected))
+		case bool:
+			testBoolObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%s: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestSetInspect(t *testing.T) {
+	tests := map[string]string{
+		`#{3, "a", 1, 3}`:   "#{3, a, 1}",
+		"#{}":               "#{}",
+		"#{2, 1} | #{3, 1}": "#{2, 1, 3}",
+		"#{1, 2}.add(0)":    "#{1, 2, 0}",
+	}
+
+	for input, expected := range tests {
+		if got := testEval(input).Inspect(); got != expected {
+			t.Errorf("%s: wrong inspect. expected=%q, got=%q", input, expected, got)
+		}
+	}
+}
+
+func TestTupleInspect(t *testing.T) {
+	tests := map[string]string{
+		`(1, "a")`:                         "(1, a)",
+		"(1,)":                             "(1,)",
+		"()":                               "()",
+		"match (1, 2) { [a, ...r] => r }": "(2,)",
+	}
+
+	for input, expected := range tests {
+		if got := testEval(input).Inspect(); got != expected {
+			t.Errorf("%s: wrong inspect. expected=%q, got=%q", input, expected, got)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package eval
+
+import (
+	"coff-src/src/coff/ast"
+	"coff-src/src/coff/object"
+)
+
+// Hooks let tools such as debuggers observe a program while it is
+// evaluated. Any of the functions may be nil.
+type Hooks struct {
+	// Node is called before each statement and expression is evaluated,
+	// with the environment it is evaluated in.
+	Node func(node ast.Node, env *object.Env)
+
+	// Call is called when a function defined in CoffLang is entered,
+	// with the environment holding its arguments.
+	Call func(fn *object.Function, args []object.Object, env *object.Env)
+
+	// Return is called when such a function returns.
+	Return func(fn *object.Function, result object.Object)
+
+	// Branch is called when an if expression has evaluated its
+	// condition, with whether the consequence is taken.
+	Branch func(node *ast.IfExpression, taken bool)
+
+	// Alloc is called when a string, array, tuple, hash, set, struct,
+	// instance or function value is created.
+	Alloc func(obj object.Object)
+}
+
+var hooks []*Hooks
+
+// AddHooks installs h until the returned function is called. It must not
+// be called while a program is being evaluated.
+func AddHooks(h *Hooks) (remove func()) {
+	hooks = append(hooks, h)
+
+	return func() {
+		for i, installed := range hooks {
+			if installed == h {
+				hooks = append(hooks[:i:i], hooks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func nodeHooks(node ast.Node, env *object.Env) {
+	for _, h := range hooks {
+		if h.Node != nil {
+			h.Node(node, env)
+		}
+	}
+}
+
+func callHooks(fn *object.Function, args []object.Object, env *object.Env) {
+	for _, h := range hooks {
+		if h.Call != nil {
+			h.Call(fn, args, env)
+		}
+	}
+}
+
+func returnHooks(fn *object.Function, result object.Object) {
+	for _, h := range hooks {
+		if h.Return != nil {
+			h.Return(fn, result)
+		}
+	}
+}
+
+func branchHooks(node *ast.IfExpression, taken bool) {
+	for _, h := range hooks {
+		if h.Branch != nil {
+			h.Branch(node, taken)
+		}
+	}
+}
+
+// allocated reports obj to the Alloc hooks and returns it.
+func allocated(obj object.Object) object.Object {
+	for _, h := range hooks {
+		if h.Alloc != nil {
+			h.Alloc(obj)
+		}
+	}
+	return obj
+}
@@ -0,0 +1,159 @@
+package eval
+
+import (
+	"coff-src/src/coff/ast"
+	"coff-src/src/coff/object"
+)
+
+// evalMatchExpression evaluates the first arm that matches. The names an
+// arm's pattern binds are visible in its guard and body only.
+func evalMatchExpression(node *ast.MatchExpression, env *object.Env) object.Object {
+	subject := Eval(node.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+	if subject == nil {
+		subject = NULL // a nil value would count as missing to the patterns
+	}
+
+	for _, arm := range node.Arms {
+		armEnv := object.NewEnclosedEnv(env)
+		ok, err := bindPattern(arm.Pattern, subject, armEnv)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		if arm.Guard != nil {
+			guard := Eval(arm.Guard, armEnv)
+			if isError(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+
+		return Eval(arm.Body, armEnv)
+	}
+
+	return newError(object.MATCH_ERROR, "no pattern matches %s", subject.Inspect())
+}
+
+// bindPattern reports whether value matches pattern, binding the names
+// in the pattern in env as it goes. A nil value stands for a missing one,
+// which only a pattern with a default matches. The error is set if
+// evaluating a default fails.
+func bindPattern(pattern ast.Pattern, value object.Object, env *object.Env) (bool, *object.Error) {
+	if value == nil {
+		dp, ok := pattern.(*ast.DefaultPattern)
+		if !ok {
+			return false, nil
+		}
+		value = Eval(dp.Default, env)
+		if err, ok := value.(*object.Error); ok {
+			return false, err
+		}
+	}
+
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value != "_" {
+			env.Set(pattern.Value, value)
+		}
+		return true, nil
+	case *ast.DefaultPattern:
+		return bindPattern(pattern.Pattern, value, env)
+	case *ast.LiteralPattern:
+		return object.Equal(Eval(pattern.Value, env), value), nil
+	case *ast.ArrPattern:
+		var elements []object.Object
+		switch value := value.(type) {
+		case *object.Arr:
+			elements = value.Elements
+		case *object.Tuple:
+			elements = value.Elements
+		default:
+			return false, nil
+		}
+		n := len(pattern.Elements)
+		if pattern.Rest == nil && len(elements) > n {
+			return false, nil
+		}
+		for i, el := range pattern.Elements {
+			var v object.Object
+			if i < len(elements) {
+				v = elements[i]
+			}
+			if ok, err := bindPattern(el, v, env); !ok {
+				return false, err
+			}
+		}
+		if pattern.Rest != nil {
+			rest := []object.Object{}
+			if len(elements) > n {
+				rest = append(rest, elements[n:]...)
+			}
+			// The rest is of the same kind as the value matched.
+			if _, ok := value.(*object.Tuple); ok {
+				bindPattern(pattern.Rest, allocated(&object.Tuple{Elements: rest}), env)
+			} else {
+				bindPattern(pattern.Rest, allocated(&object.Arr{Elements: rest}), env)
+			}
+		}
+		return true, nil
+	case *ast.HashPattern:
+		for _, key := range pattern.Keys {
+			v, ok := patternMember(value, Eval(key, env))
+			if !ok {
+				return false, nil
+			}
+			if ok, err := bindPattern(pattern.Values[key], v, env); !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// patternMember looks up key in value for a hash pattern. Hashes are
+// looked up by key, structs and instances by field name. The member is
+// nil if value has no such key, and ok is false if value cannot be
+// matched by a hash pattern at all.
+func patternMember(value, key object.Object) (member object.Object, ok bool) {
+	switch value := value.(type) {
+	case *object.Hash:
+		member, _ = value.Get(key)
+		return member, true
+	case *object.Struct:
+		if name, ok := key.(*object.Str); ok {
+			member, _ = value.Get(name.Value)
+		}
+		return member, true
+	case *object.Instance:
+		if name, ok := key.(*object.Str); ok {
+			member = value.Fields[name.Value]
+		}
+		return member, true
+	}
+
+	return nil, false
+}
+
+// destructure binds the names in pattern to the parts of value, or
+// returns an error if value does not have the shape of pattern.
+func destructure(pattern ast.Pattern, value object.Object, env *object.Env) *object.Error {
+	ok, err := bindPattern(pattern, value, env)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return newError(object.MATCH_ERROR, "%s does not match %s", value.Inspect(), pattern.String())
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package eval
+
+import (
+	"coff-src/src/coff/ast"
+	"coff-src/src/coff/object"
+)
+
+func evalMemberExpression(node *ast.MemberExpression, env *object.Env) object.Object {
+	obj := Eval(node.Object, env)
+	if isError(obj) {
+		return obj
+	}
+	name := node.Member.Value
+
+	switch obj := obj.(type) {
+	case *object.Module:
+		value, ok := obj.Get(name)
+		if !ok {
+			return newError(object.MEMBER_ERROR, "module %s has no exported member %s", DisplayPath(obj.Path), name)
+		}
+		return value
+	case *object.Instance:
+		return instanceMember(obj, name)
+	case *object.Super:
+		return superMember(obj, name)
+	case *object.Struct:
+		value, ok := obj.Get(name)
+		if !ok {
+			return newError(object.MEMBER_ERROR, "%s has no field %s", obj.Def.Name, name)
+		}
+		return value
+	case *object.Hash:
+		value, ok := obj.Get(memberKey(name))
+		if !ok {
+			return newError(object.MEMBER_ERROR, "hash has no member %s", name)
+		}
+		return value
+	case *object.ErrorValue:
+		return errorMember(obj, name)
+	case *object.Set:
+		return setMember(obj, name)
+	default:
+		return newError(object.TYPE_ERROR, "member access is not supported: %s", obj.Type())
+	}
+}
+
+func evalAssignStatement(node *ast.AssignStatement, env *object.Env) object.Object {
+	target, ok := node.Target.(*ast.MemberExpression)
+	if !ok {
+		return newError(object.TYPE_ERROR, "cannot assign to %s", node.Target.String())
+	}
+
+	obj := Eval(target.Object, env)
+	if isError(obj) {
+		return obj
+	}
+	value := Eval(node.Value, env)
+	if isError(value) {
+		return value
+	}
+	name := target.Member.Value
+
+	switch obj := obj.(type) {
+	case *object.Instance:
+		obj.Set(name, value)
+		return nil
+	case *object.Struct:
+		if !obj.Set(name, value) {
+			return newError(object.MEMBER_ERROR, "%s has no field %s", obj.Def.Name, name)
+		}
+		return nil
+	case *object.Hash:
+		obj.Set(memberKey(name), value)
+		return nil
+	case *object.Module:
+		return newError(object.TYPE_ERROR, "cannot assign to member %s of module %s", name, DisplayPath(obj.Path))
+	default:
+		return newError(object.TYPE_ERROR, "member assignment is not supported: %s", obj.Type())
+	}
+}
+
+// memberKey returns the hash key that member name stands for.
+func memberKey(name string) *object.Str {
+	return &object.Str{Value: name}
+}
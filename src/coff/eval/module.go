@@ -0,0 +1,160 @@
+package eval
+
+import (
+	"coff-src/src/coff/ast"
+	"coff-src/src/coff/lexer"
+	"coff-src/src/coff/object"
+	"coff-src/src/coff/parser"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SearchPath lists the directories searched for imported modules that
+// are not found relative to the importing file.
+var SearchPath []string
+
+var (
+	// modules caches the modules imported so far by absolute path, so
+	// that each is evaluated only once.
+	modules = map[string]*object.Module{}
+
+	// importing holds the paths of the modules being evaluated, the
+	// innermost last, to detect import cycles.
+	importing []string
+)
+
+func evalImportStatement(node *ast.ImportStatement, env *object.Env) object.Object {
+	module := importModule(node.Path.Value, env.Path())
+	if isError(module) {
+		return module
+	}
+	env.Set(node.Name.Value, module)
+
+	return nil
+}
+
+// evalModule evaluates the program of a module with importing set to
+// chain. importing is restored even if the evaluation is abandoned by a
+// panic, as the debugger does on Stop.
+func evalModule(program *ast.Program, env *object.Env, chain []string) object.Object {
+	outer := importing
+	importing = chain
+	defer func() { importing = outer }()
+
+	return Eval(program, env)
+}
+
+// importModule returns the module at path, resolved relative to the
+// file from, evaluating it if it has not been imported before.
+func importModule(path string, from string) object.Object {
+	resolved, ok := resolveModule(path, from)
+	if !ok {
+		return newError(object.IMPORT_ERROR, "module not found: %q", path)
+	}
+
+	// The chain of imports starts at the file run, which is not itself
+	// imported.
+	chain := importing
+	if len(chain) == 0 && from != "" {
+		if abs, err := filepath.Abs(from); err == nil {
+			chain = []string{abs}
+		}
+	}
+	for i, p := range chain {
+		if p == resolved {
+			cycle := []string{}
+			for _, p := range append(chain[i:], resolved) {
+				cycle = append(cycle, DisplayPath(p))
+			}
+			return newError(object.IMPORT_ERROR, "import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	if module, ok := modules[resolved]; ok {
+		return module
+	}
+
+	src, err := os.ReadFile(resolved)
+	if err != nil {
+		return newError(object.IMPORT_ERROR, "cannot import %q: %s", path, err)
+	}
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return newError(object.IMPORT_ERROR, "cannot import %q: %s", path, p.Errors()[0])
+	}
+
+	env := object.NewEnv()
+	env.SetPath(resolved)
+
+	result := evalModule(program, env, append(chain, resolved))
+	if isError(result) {
+		return result
+	}
+
+	module := &object.Module{Path: resolved, Env: env, Exports: map[string]bool{}}
+	for _, stmt := range program.Statements {
+		switch stmt := stmt.(type) {
+		case *ast.DefStatement:
+			if !stmt.Exported() {
+				continue
+			}
+			if stmt.Pattern != nil {
+				for _, id := range ast.Bindings(stmt.Pattern) {
+					module.Exports[id.Value] = true
+				}
+			} else {
+				module.Exports[stmt.Name.Value] = true
+			}
+		case *ast.StructStatement:
+			if stmt.Exported() {
+				module.Exports[stmt.Name.Value] = true
+			}
+		case *ast.ClassStatement:
+			if stmt.Exported() {
+				module.Exports[stmt.Name.Value] = true
+			}
+		}
+	}
+	modules[resolved] = module
+
+	return module
+}
+
+// resolveModule finds the file path refers to: relative to the directory
+// of from, or of the working directory if from is "", and then to each
+// directory of SearchPath. It returns the file's absolute path.
+func resolveModule(path string, from string) (string, bool) {
+	candidates := []string{path}
+	if !filepath.IsAbs(path) {
+		candidates = []string{filepath.Join(filepath.Dir(from), path)}
+		for _, dir := range SearchPath {
+			candidates = append(candidates, filepath.Join(dir, path))
+		}
+	}
+
+	for _, c := range candidates {
+		info, err := os.Stat(c)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if abs, err := filepath.Abs(c); err == nil {
+			return abs, true
+		}
+	}
+	return "", false
+}
+
+// DisplayPath shortens path to be relative to the working directory if
+// it is below it.
+func DisplayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
@@ -0,0 +1,169 @@
+package eval
+
+import (
+	"coff-src/src/coff/ast"
+	"coff-src/src/coff/object"
+)
+
+func evalSetLiteral(node *ast.SetLiteral, env *object.Env) object.Object {
+	set := object.NewSet()
+
+	for _, elementNode := range node.Elements {
+		element := Eval(elementNode, env)
+		if isError(element) {
+			return element
+		}
+
+		if !set.Add(element) {
+			return newError(object.TYPE_ERROR, "unusable as set element: %s", element.Type())
+		}
+	}
+
+	return allocated(set)
+}
+
+// evalSetInfixExpression returns the union (|), intersection (&),
+// difference (-) or symmetric difference (^) of two sets as a new set.
+func evalSetInfixExpression(operator string, left, right *object.Set) object.Object {
+	result := object.NewSet()
+
+	switch operator {
+	case "|":
+		addAll(result, left.Elements())
+		addAll(result, right.Elements())
+	case "&":
+		for _, el := range left.Elements() {
+			if right.Has(el) {
+				result.Add(el)
+			}
+		}
+	case "-":
+		for _, el := range left.Elements() {
+			if !right.Has(el) {
+				result.Add(el)
+			}
+		}
+	case "^":
+		for _, el := range left.Elements() {
+			if !right.Has(el) {
+				result.Add(el)
+			}
+		}
+		for _, el := range right.Elements() {
+			if !left.Has(el) {
+				result.Add(el)
+			}
+		}
+	default:
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+
+	return allocated(result)
+}
+
+// addAll adds elements to set and returns the first that cannot be a set
+// element, or nil.
+func addAll(set *object.Set, elements []object.Object) object.Object {
+	for _, el := range elements {
+		if !set.Add(el) {
+			return el
+		}
+	}
+	return nil
+}
+
+func init() {
+	stds["set"] = &object.Std{
+		Signature: "set(x)",
+		Doc:       "Returns a set of the elements of an array or tuple.",
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			var elements []object.Object
+			switch arg := args[0].(type) {
+			case *object.Arr:
+				elements = arg.Elements
+			case *object.Tuple:
+				elements = arg.Elements
+			case *object.Set:
+				return arg
+			default:
+				return newError(object.TYPE_ERROR, "argument to `set` must be ARRAY or TUPLE, got %s", args[0].Type())
+			}
+
+			set := object.NewSet()
+			if el := addAll(set, elements); el != nil {
+				return newError(object.TYPE_ERROR, "unusable as set element: %s", el.Type())
+			}
+			return allocated(set)
+		},
+	}
+	stds["array"] = &object.Std{
+		Signature: "array(x)",
+		Doc:       "Returns an array of the elements of a tuple or set or the characters of a string, in order.",
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Arr:
+				return arg
+			case *object.Str:
+				elements := []object.Object{}
+				for _, char := range arg.Value {
+					elements = append(elements, allocated(&object.Str{Value: string(char)}))
+				}
+				return allocated(&object.Arr{Elements: elements})
+			case *object.Tuple:
+				elements := make([]object.Object, len(arg.Elements))
+				copy(elements, arg.Elements)
+				return allocated(&object.Arr{Elements: elements})
+			case *object.Set:
+				return allocated(&object.Arr{Elements: arg.Elements()})
+			default:
+				return newError(object.TYPE_ERROR, "argument to `array` must be TUPLE, SET or STR, got %s", args[0].Type())
+			}
+		},
+	}
+}
+
+// setMember returns the method of a set called name. Sets are values, so
+// add and remove return a new set rather than changing s.
+func setMember(s *object.Set, name string) object.Object {
+	var change func(result *object.Set, x object.Object) object.Object
+	switch name {
+	case "add":
+		change = func(result *object.Set, x object.Object) object.Object {
+			if !result.Add(x) {
+				return newError(object.TYPE_ERROR, "unusable as set element: %s", x.Type())
+			}
+			return nil
+		}
+	case "remove":
+		change = func(result *object.Set, x object.Object) object.Object {
+			result.Remove(x)
+			return nil
+		}
+	default:
+		return newError(object.MEMBER_ERROR, "set has no member %s", name)
+	}
+
+	return &object.Std{
+		Signature: name + "(x)",
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			result := object.NewSet()
+			addAll(result, s.Elements())
+			if err := change(result, args[0]); err != nil {
+				return err
+			}
+			return allocated(result)
+		},
+	}
+}
@@ -2,34 +2,66 @@ package eval
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"sort"
+	"unicode/utf8"
 	"coff-src/src/coff/object"
 )
 
+// Stdout is where print writes.
+var Stdout io.Writer = os.Stdout
+
+// LookupStd returns the built-in function called name.
+func LookupStd(name string) (*object.Std, bool) {
+	std, ok := stds[name]
+	return std, ok
+}
+
+// Builtins returns the names of the built-in functions in sorted order.
+func Builtins() []string {
+	names := []string{}
+	for name := range stds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
 var stds = map[string]*object.Std{
 	"len": &object.Std{
+		Signature: "len(x)",
+		Doc: "Returns the number of elements of an array, tuple or set or the number of characters of a string.",
 		Fun: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
 			}
 			
 			switch arg := args[0].(type) {
 			case *object.Arr:
 				return &object.Int{Value: int64(len(arg.Elements))}
+			case *object.Tuple:
+				return &object.Int{Value: int64(len(arg.Elements))}
+			case *object.Set:
+				return &object.Int{Value: int64(arg.Len())}
 			case *object.Str:
-				return &object.Int{Value: int64(len(arg.Value))}
+				return &object.Int{Value: int64(utf8.RuneCountInString(arg.Value))}
 			default:
-				return newError("argument to `len` is not supported, got %s", args[0].Type())
+				return newError(object.TYPE_ERROR, "argument to `len` is not supported, got %s", args[0].Type())
 			}
 		},
 	},
 	"first": &object.Std{
+		Signature: "first(arr)",
+		Doc: "Returns the first element of an array, or null if it is empty.",
 		Fun: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
 			}
 
 			if args[0].Type() != object.ARR_OBJ {
-				return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
+				return newError(object.TYPE_ERROR, "argument to `first` must be ARRAY, got %s", args[0].Type())
 			}
 
 			arr := args[0].(*object.Arr)
@@ -40,13 +72,15 @@ var stds = map[string]*object.Std{
 		},
 	},
 	"last": &object.Std{
+		Signature: "last(arr)",
+		Doc: "Returns the last element of an array, or null if it is empty.",
 		Fun: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
 			}
 		
 			if args[0].Type() != object.ARR_OBJ {
-				return newError("argument to `last` must be ARRAY, got %s", args[0].Type())
+				return newError(object.TYPE_ERROR, "argument to `last` must be ARRAY, got %s", args[0].Type())
 			}
 		
 			arr := args[0].(*object.Arr)
@@ -58,12 +92,14 @@ var stds = map[string]*object.Std{
 		},
 	},
 	"rest": &object.Std{
+		Signature: "rest(arr)",
+		Doc: "Returns a new array with all elements but the first, or null if arr is empty.",
 		Fun: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
 			}
 			if args[0].Type() != object.ARR_OBJ {
-				return newError("argument to `rest` must be ARRAY, got %s", args[0].Type())
+				return newError(object.TYPE_ERROR, "argument to `rest` must be ARRAY, got %s", args[0].Type())
 			}
 
 			arr := args[0].(*object.Arr)
@@ -72,20 +108,22 @@ var stds = map[string]*object.Std{
 			if length > 0 {
 				newElements := make([]object.Object, length-1, length-1)
 				copy(newElements, arr.Elements[1:length])
-				return &object.Arr{Elements: newElements}
+				return allocated(&object.Arr{Elements: newElements})
 			}
 
 			return NULL
 		},
 	},
 	"push": &object.Std{
+		Signature: "push(arr, x)",
+		Doc: "Returns a new array with x appended to arr.",
 		Fun: func(args ...object.Object) object.Object {
 			if len(args) != 2 {
-				return newError("wrong number of arguments. got=%d, want=2", len(args))
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=2", len(args))
 			}
 		
 			if args[0].Type() != object.ARR_OBJ {
-				return newError("argument to `push` must be ARRAY, got %s", args[0].Type())
+				return newError(object.TYPE_ERROR, "argument to `push` must be ARRAY, got %s", args[0].Type())
 			}
 		
 			arr := args[0].(*object.Arr)
@@ -95,16 +133,40 @@ var stds = map[string]*object.Std{
 			copy(newElements, arr.Elements)
 			newElements[length] = args[1]
 			
-			return &object.Arr{Elements: newElements}
+			return allocated(&object.Arr{Elements: newElements})
+		},
+	},
+	"tuple": &object.Std{
+		Signature: "tuple(x)",
+		Doc: "Returns a tuple of the elements of an array or set, which can be used as a hash key.",
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Arr:
+				elements := make([]object.Object, len(arg.Elements))
+				copy(elements, arg.Elements)
+				return allocated(&object.Tuple{Elements: elements})
+			case *object.Tuple:
+				return arg
+			case *object.Set:
+				return allocated(&object.Tuple{Elements: arg.Elements()})
+			default:
+				return newError(object.TYPE_ERROR, "argument to `tuple` must be ARRAY or SET, got %s", args[0].Type())
+			}
 		},
 	},
 	"print": &object.Std{
+		Signature: "print(args...)",
+		Doc: "Prints each argument on its own line and returns null.",
 		Fun: func(args ...object.Object) object.Object {
 			for _, arg := range args {
-				fmt.Println(arg.Inspect())
+				fmt.Fprintln(Stdout, arg.Inspect())
 			}
 			
 			return NULL
 		},
 	},
-}
\ No newline at end of file
+}
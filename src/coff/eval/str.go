@@ -0,0 +1,84 @@
+package eval
+
+import (
+	"coff-src/src/coff/object"
+	"unicode/utf8"
+)
+
+func init() {
+	stds["bytes"] = &object.Std{
+		Signature: "bytes(s)",
+		Doc:       "Returns an array of the bytes of the UTF-8 encoding of a string.",
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+			str, ok := args[0].(*object.Str)
+			if !ok {
+				return newError(object.TYPE_ERROR, "argument to `bytes` must be STR, got %s", args[0].Type())
+			}
+
+			elements := make([]object.Object, len(str.Value))
+			for i := 0; i < len(str.Value); i++ {
+				elements[i] = &object.Int{Value: int64(str.Value[i])}
+			}
+			return allocated(&object.Arr{Elements: elements})
+		},
+	}
+	stds["runes"] = &object.Std{
+		Signature: "runes(s)",
+		Doc:       "Returns an array of the code points of a string.",
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+			str, ok := args[0].(*object.Str)
+			if !ok {
+				return newError(object.TYPE_ERROR, "argument to `runes` must be STR, got %s", args[0].Type())
+			}
+
+			elements := []object.Object{}
+			for _, char := range str.Value {
+				elements = append(elements, &object.Int{Value: int64(char)})
+			}
+			return allocated(&object.Arr{Elements: elements})
+		},
+	}
+	stds["ord"] = &object.Std{
+		Signature: "ord(c)",
+		Doc:       "Returns the code point of a one-character string.",
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+			str, ok := args[0].(*object.Str)
+			if !ok {
+				return newError(object.TYPE_ERROR, "argument to `ord` must be STR, got %s", args[0].Type())
+			}
+			if n := utf8.RuneCountInString(str.Value); n != 1 {
+				return newError(object.ARGUMENT_ERROR, "argument to `ord` must be a single character, got %d characters", n)
+			}
+
+			char, _ := utf8.DecodeRuneInString(str.Value)
+			return &object.Int{Value: int64(char)}
+		},
+	}
+	stds["chr"] = &object.Std{
+		Signature: "chr(n)",
+		Doc:       "Returns the one-character string with code point n.",
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+			n, ok := args[0].(*object.Int)
+			if !ok {
+				return newError(object.TYPE_ERROR, "argument to `chr` must be INT, got %s", args[0].Type())
+			}
+			if n.Value < 0 || n.Value > utf8.MaxRune || !utf8.ValidRune(rune(n.Value)) {
+				return newError(object.ARGUMENT_ERROR, "invalid code point: %d", n.Value)
+			}
+
+			return allocated(&object.Str{Value: string(rune(n.Value))})
+		},
+	}
+}
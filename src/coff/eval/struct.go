@@ -0,0 +1,29 @@
+package eval
+
+import (
+	"coff-src/src/coff/ast"
+	"coff-src/src/coff/object"
+)
+
+func evalStructStatement(node *ast.StructStatement, env *object.Env) object.Object {
+	def := &object.StructType{Name: node.Name.Value}
+	for _, f := range node.Fields {
+		def.Fields = append(def.Fields, f.Value)
+	}
+	env.Set(node.Name.Value, def)
+
+	return nil
+}
+
+// newStruct calls the constructor of def, which takes the value of each
+// field in declaration order.
+func newStruct(def *object.StructType, args []object.Object) object.Object {
+	if len(args) != len(def.Fields) {
+		return newError(object.ARGUMENT_ERROR, "wrong number of arguments to %s. got=%d, want=%d", def.Name, len(args), len(def.Fields))
+	}
+
+	values := make([]object.Object, len(args))
+	copy(values, args)
+
+	return allocated(&object.Struct{Def: def, Values: values})
+}
@@ -0,0 +1,562 @@
+// Package format prints CoffLang programs in the canonical source style:
+// one statement per line, tab indentation, single spaces around binary
+// operators, and only the parentheses the grammar needs. Comments are
+// kept next to the statements they belong to.
+package format
+
+import (
+	"bytes"
+	"coff-src/src/coff/ast"
+	"coff-src/src/coff/lexer"
+	"coff-src/src/coff/parser"
+	"coff-src/src/coff/token"
+	"errors"
+	"strings"
+)
+
+// Source formats src. It returns an error if src does not parse.
+func Source(src []byte) ([]byte, error) {
+	l := lexer.New(string(src))
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		return nil, errors.New(strings.Join(p.Errors(), "\n"))
+	}
+
+	pr := &printer{comments: l.Comments()}
+	pr.program(program)
+
+	return pr.out.Bytes(), nil
+}
+
+// Node returns the canonical form of a single node, without comments.
+func Node(node ast.Node) string {
+	pr := &printer{}
+
+	switch node := node.(type) {
+	case *ast.Program:
+		pr.program(node)
+		return strings.TrimSuffix(pr.out.String(), "\n")
+	case ast.Statement:
+		pr.statement(node, nil)
+	case ast.Expression:
+		pr.expression(node, parser.LOWEST)
+	}
+
+	return pr.out.String()
+}
+
+type printer struct {
+	out bytes.Buffer
+
+	indent int
+	bol    bool // at the beginning of an output line
+
+	comments []token.Token
+	next     int // index of the first comment not yet printed
+	last     int // source line of the last thing printed
+}
+
+func (p *printer) write(s string) {
+	if p.bol {
+		p.out.WriteString(strings.Repeat("\t", p.indent))
+		p.bol = false
+	}
+	p.out.WriteString(s)
+}
+
+func (p *printer) newline() {
+	p.out.WriteString("\n")
+	p.bol = true
+}
+
+// gap starts a new output line for something found at source line
+// line, keeping at most one blank line from the source.
+func (p *printer) gap(line int) {
+	if p.out.Len() == 0 {
+		return
+	}
+
+	p.newline()
+	if p.last != 0 && line-p.last > 1 {
+		p.newline()
+	}
+}
+
+// flush prints, each on its own line, the comments that start before
+// source line line.
+func (p *printer) flush(line int) {
+	for p.next < len(p.comments) && p.comments[p.next].Line < line {
+		c := p.comments[p.next]
+		p.gap(c.Line)
+		p.write(strings.TrimRight(c.Literal, " \t\r"))
+		p.last = c.Line
+		p.next++
+	}
+}
+
+// trailing prints a comment found on source line line after the code
+// already printed on the current output line.
+func (p *printer) trailing(line int) {
+	if p.next < len(p.comments) && p.comments[p.next].Line == line {
+		p.write(" " + strings.TrimRight(p.comments[p.next].Literal, " \t\r"))
+		p.next++
+	}
+}
+
+func (p *printer) program(program *ast.Program) {
+	p.statements(program.Statements)
+	p.flush(int(^uint(0) >> 1))
+
+	if p.out.Len() != 0 {
+		p.newline()
+	}
+}
+
+func (p *printer) statements(stmts []ast.Statement) {
+	for i, s := range stmts {
+		p.flush(startLine(s))
+		p.gap(startLine(s))
+
+		var next ast.Statement
+		if i+1 < len(stmts) {
+			next = stmts[i+1]
+		}
+		p.statement(s, next)
+
+		p.last = endLine(s)
+		p.trailing(p.last)
+	}
+}
+
+// statement prints s. next is the statement that follows s, if any; it
+// decides whether a statement ending in a block needs a semicolon.
+func (p *printer) statement(s ast.Statement, next ast.Statement) {
+	switch s := s.(type) {
+	case *ast.DefStatement:
+		if s.Exported() {
+			p.write("export ")
+		}
+		p.write("def ")
+		if s.Pattern != nil {
+			p.pattern(s.Pattern)
+		} else {
+			p.write(s.Name.Value)
+		}
+		p.write(" = ")
+		p.expression(s.Value, parser.LOWEST)
+		p.write(";")
+	case *ast.RetStatement:
+		p.write("ret ")
+		p.expression(s.RetVal, parser.LOWEST)
+		p.write(";")
+	case *ast.ThrowStatement:
+		p.write("throw ")
+		p.expression(s.Value, parser.LOWEST)
+		p.write(";")
+	case *ast.AssignStatement:
+		p.expression(s.Target, parser.LOWEST)
+		p.write(" = ")
+		p.expression(s.Value, parser.LOWEST)
+		p.write(";")
+	case *ast.StructStatement:
+		p.write(s.String())
+	case *ast.ClassStatement:
+		p.class(s)
+	case *ast.ImportStatement:
+		p.write("import \"" + s.Path.Value + "\" as " + s.Name.Value + ";")
+	case *ast.ExpressionStatement:
+		p.expression(s.Expression, parser.LOWEST)
+		if !endsWithBlock(s.Expression) || continuesExpression(next) {
+			p.write(";")
+		}
+	case *ast.BlockStatement:
+		p.block(s)
+	}
+}
+
+// class prints a class declaration with one method per line.
+func (p *printer) class(c *ast.ClassStatement) {
+	if c.Exported() {
+		p.write("export ")
+	}
+	p.write("class " + c.Name.Value)
+	if c.Super != nil {
+		p.write(" < " + c.Super.Value)
+	}
+	p.write(" {")
+
+	p.indent++
+	p.last = 0
+	start := p.out.Len()
+	for _, m := range c.Methods {
+		p.flush(m.Token.Line)
+		p.gap(m.Token.Line)
+		p.write(m.Name)
+		p.parameters(m)
+		p.block(m.Body)
+		p.last = m.Body.EndToken.Line
+		p.trailing(p.last)
+	}
+	p.flush(c.EndToken.Line)
+	p.indent--
+
+	if p.out.Len() != start {
+		p.newline()
+	}
+	p.write("}")
+	p.last = c.EndToken.Line
+}
+
+func (p *printer) block(b *ast.BlockStatement) {
+	if len(b.Statements) == 1 && b.Token.Line == b.EndToken.Line {
+		p.write("{ ")
+		switch s := b.Statements[0].(type) {
+		case *ast.ExpressionStatement:
+			p.expression(s.Expression, parser.LOWEST)
+		default:
+			p.statement(s, nil)
+		}
+		p.write(" }")
+		return
+	}
+
+	p.write("{")
+	p.indent++
+	p.last = 0
+	start := p.out.Len()
+	p.statements(b.Statements)
+	p.flush(b.EndToken.Line)
+	p.indent--
+
+	if p.out.Len() != start {
+		p.newline()
+	}
+	p.write("}")
+	p.last = b.EndToken.Line
+}
+
+func (p *printer) expression(e ast.Expression, prec int) {
+	if precedence(e) < prec {
+		p.write("(")
+		p.expression(e, parser.LOWEST)
+		p.write(")")
+		return
+	}
+
+	switch e := e.(type) {
+	case *ast.Identifier:
+		p.write(e.Value)
+	case *ast.IntLiteral:
+		p.write(e.Token.Literal)
+	case *ast.Boolean:
+		p.write(e.Token.Literal)
+	case *ast.StrLiteral:
+		p.write("\"" + e.Value + "\"")
+	case *ast.PrefixExpression:
+		p.write(e.Operator)
+		if e.Token.Type == token.NOT {
+			p.write(" ")
+		}
+		p.expression(e.Right, parser.PrefixPrecedence(e.Token.Type))
+	case *ast.InfixExpression:
+		prec := parser.Precedence(e.Token.Type)
+		left, right := prec, prec+1
+		if parser.RightAssociative(e.Token.Type) {
+			left, right = prec+1, prec
+		}
+		p.expression(e.Left, left)
+		p.write(" " + e.Operator + " ")
+		p.expression(e.Right, right)
+	case *ast.IfExpression:
+		p.write("if (")
+		p.expression(e.Condition, parser.LOWEST)
+		p.write(") ")
+		p.block(e.Consequence)
+		if e.Alternative != nil {
+			p.write(" else ")
+			p.block(e.Alternative)
+		}
+	case *ast.FunctionLiteral:
+		p.write("fun")
+		p.parameters(e)
+		p.block(e.Body)
+	case *ast.CallExpression:
+		p.expression(e.Function, parser.CALL)
+		p.write("(")
+		p.list(e.Arguments)
+		p.write(")")
+	case *ast.IdxExpression:
+		p.expression(e.Left, parser.CALL)
+		p.write("[")
+		p.expression(e.Index, parser.LOWEST)
+		p.write("]")
+	case *ast.SliceExpression:
+		p.expression(e.Left, parser.CALL)
+		p.write("[")
+		if e.Low != nil {
+			p.expression(e.Low, parser.LOWEST)
+		}
+		p.write(":")
+		if e.High != nil {
+			p.expression(e.High, parser.LOWEST)
+		}
+		if e.Step != nil {
+			p.write(":")
+			p.expression(e.Step, parser.LOWEST)
+		}
+		p.write("]")
+	case *ast.MemberExpression:
+		p.expression(e.Object, parser.CALL)
+		p.write("." + e.Member.Value)
+	case *ast.ArrLiteral:
+		p.write("[")
+		p.elements(e.Token.Line, e.Elements, nil, false)
+		p.write("]")
+	case *ast.TupleLiteral:
+		p.write("(")
+		p.list(e.Elements)
+		if len(e.Elements) == 1 {
+			p.write(",")
+		}
+		p.write(")")
+	case *ast.SetLiteral:
+		p.write("#{")
+		p.list(e.Elements)
+		p.write("}")
+	case *ast.HashLiteral:
+		p.write("{")
+		p.elements(e.Token.Line, e.Keys, e.Pairs, true)
+		p.write("}")
+	case *ast.MatchExpression:
+		p.match(e)
+	case *ast.TryExpression:
+		p.write("try ")
+		p.block(e.Body)
+		if e.Catch != nil {
+			p.write(" catch ")
+			if e.Param != nil {
+				p.write("(" + e.Param.Value + ") ")
+			}
+			p.block(e.Catch)
+		}
+		if e.Finally != nil {
+			p.write(" finally ")
+			p.block(e.Finally)
+		}
+	}
+}
+
+// match prints a match expression. Like hash literals, matches that
+// spanned several lines in the source get one arm per line and a
+// trailing comma.
+func (p *printer) match(m *ast.MatchExpression) {
+	p.write("match ")
+	p.expression(m.Subject, parser.LOWEST)
+	p.write(" {")
+
+	multiline := false
+	for _, arm := range m.Arms {
+		if startLine(arm.Pattern) != m.Token.Line {
+			multiline = true
+		}
+	}
+
+	if !multiline {
+		for i, arm := range m.Arms {
+			if i > 0 {
+				p.write(",")
+			}
+			p.write(" ")
+			p.arm(arm)
+		}
+		if len(m.Arms) > 0 {
+			p.write(" ")
+		}
+		p.write("}")
+		return
+	}
+
+	p.indent++
+	p.last = 0
+	for _, arm := range m.Arms {
+		p.flush(startLine(arm.Pattern))
+		p.gap(startLine(arm.Pattern))
+		p.arm(arm)
+		p.write(",")
+		p.last = endLine(arm.Body)
+		p.trailing(p.last)
+	}
+	p.flush(m.EndToken.Line)
+	p.indent--
+	p.newline()
+	p.write("}")
+	p.last = m.EndToken.Line
+}
+
+func (p *printer) arm(arm *ast.MatchArm) {
+	p.pattern(arm.Pattern)
+	if arm.Guard != nil {
+		p.write(" if ")
+		p.expression(arm.Guard, parser.LOWEST)
+	}
+	p.write(" => ")
+	p.expression(arm.Body, parser.LOWEST)
+}
+
+func (p *printer) pattern(pat ast.Pattern) {
+	switch pat := pat.(type) {
+	case *ast.Identifier:
+		p.write(pat.Value)
+	case *ast.LiteralPattern:
+		p.expression(pat.Value, parser.LOWEST)
+	case *ast.DefaultPattern:
+		p.pattern(pat.Pattern)
+		p.write(" = ")
+		p.expression(pat.Default, parser.LOWEST)
+	case *ast.ArrPattern:
+		p.write("[")
+		for i, el := range pat.Elements {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.pattern(el)
+		}
+		if pat.Rest != nil {
+			if len(pat.Elements) > 0 {
+				p.write(", ")
+			}
+			p.write("..." + pat.Rest.Value)
+		}
+		p.write("]")
+	case *ast.HashPattern:
+		p.write("{")
+		for i, k := range pat.Keys {
+			if i > 0 {
+				p.write(", ")
+			}
+			if !ast.IsShorthand(k) {
+				p.expression(k, parser.LOWEST)
+				p.write(": ")
+			}
+			p.pattern(pat.Values[k])
+		}
+		p.write("}")
+	}
+}
+
+// parameters prints the parameter list of fn and the space before its
+// body.
+func (p *printer) parameters(fn *ast.FunctionLiteral) {
+	p.write("(")
+	for i, param := range fn.Parameters {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.pattern(param)
+	}
+	p.write(") ")
+}
+
+func (p *printer) list(exps []ast.Expression) {
+	for i, e := range exps {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.expression(e, parser.LOWEST)
+	}
+}
+
+// elements prints the contents of an array or hash literal. Literals
+// that spanned several lines in the source get one element per line;
+// hash literals then also get a trailing comma.
+func (p *printer) elements(line int, keys []ast.Expression, values map[ast.Expression]ast.Expression, trailingComma bool) {
+	multiline := false
+	for _, k := range keys {
+		if startLine(k) != line {
+			multiline = true
+		}
+	}
+
+	if !multiline {
+		for i, k := range keys {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.element(k, values)
+		}
+		return
+	}
+
+	p.indent++
+	p.last = 0
+	for i, k := range keys {
+		p.flush(startLine(k))
+		p.gap(startLine(k))
+		p.element(k, values)
+		if i+1 < len(keys) || trailingComma {
+			p.write(",")
+		}
+		p.last = endLine(k)
+		if values != nil {
+			p.last = endLine(values[k])
+		}
+		p.trailing(p.last)
+	}
+	p.indent--
+	p.newline()
+}
+
+func (p *printer) element(key ast.Expression, values map[ast.Expression]ast.Expression) {
+	p.expression(key, parser.LOWEST)
+	if values != nil {
+		p.write(": ")
+		p.expression(values[key], parser.LOWEST)
+	}
+}
+
+// precedence returns how tightly e binds; operands that bind less
+// tightly than their context requires are parenthesized. Calls, indexing
+// and member access all apply to the operand on their left, so their
+// operands need parentheses only if they bind less tightly than a call.
+func precedence(e ast.Expression) int {
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(e.Token.Type)
+	case *ast.PrefixExpression:
+		return parser.PrefixPrecedence(e.Token.Type)
+	case *ast.CallExpression:
+		return parser.CALL
+	case *ast.IdxExpression, *ast.SliceExpression:
+		return parser.INDEX
+	case *ast.MemberExpression:
+		return parser.MEMBER
+	default:
+		return parser.MEMBER + 1
+	}
+}
+
+func endsWithBlock(e ast.Expression) bool {
+	switch e.(type) {
+	case *ast.IfExpression, *ast.FunctionLiteral, *ast.MatchExpression, *ast.TryExpression:
+		return true
+	}
+	return false
+}
+
+// continuesExpression reports whether s starts with a token that the
+// parser would read as an infix operator applied to the statement
+// before it, such as the '(' of a grouped expression.
+func continuesExpression(s ast.Statement) bool {
+	es, ok := s.(*ast.ExpressionStatement)
+	return ok && parser.Precedence(es.Token.Type) > parser.LOWEST
+}
+
+func startLine(n ast.Node) int {
+	return ast.Start(n).Line
+}
+
+func endLine(n ast.Node) int {
+	return ast.End(n).Line
+}
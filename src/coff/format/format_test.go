@@ -0,0 +1,203 @@
+package format
+
+import (
+	"coff-src/src/coff/lexer"
+	"coff-src/src/coff/parser"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"def x=5", "def x = 5;\n"},
+		{"ret   x", "ret x;\n"},
+		{"a+b*c", "a + b * c;\n"},
+		{"(a+b)*c", "(a + b) * c;\n"},
+		{"a-(b-c)", "a - (b - c);\n"},
+		{"(a-b)-c", "a - b - c;\n"},
+		{"-(a+b)", "-(a + b);\n"},
+		{"!(-a)", "!-a;\n"},
+		{"(-a)(b)", "(-a)(b);\n"},
+		{"(a+b)[0]", "(a + b)[0];\n"},
+		{"f(x)[0]", "f(x)[0];\n"},
+		{"(a.b)[0].c(1)", "a.b[0].c(1);\n"},
+		{"a[1 :2]", "a[1:2];\n"},
+		{"a[ : -1]", "a[:-1];\n"},
+		{"(a+b)[::2]", "(a + b)[::2];\n"},
+		{"a[i:][0]", "a[i:][0];\n"},
+		{"(-a).b", "(-a).b;\n"},
+		{`import  "lib.coff"as lib`, "import \"lib.coff\" as lib;\n"},
+		{"export   def x=1", "export def x = 1;\n"},
+		{"a.b.c=1+2", "a.b.c = 1 + 2;\n"},
+		{"struct Point{x,y};", "struct Point { x, y }\n"},
+		{"export struct Empty{}", "export struct Empty {}\n"},
+		{"class A<B{f(x){x}g(){}}", "class A < B {\n\tf(x) { x }\n\tg() {}\n}\n"},
+		{"class A{}", "class A {}\n"},
+		{"def [a,[b,c=1],...d]=x", "def [a, [b, c = 1], ...d] = x;\n"},
+		{"def {name,age=1,\"k\":{v}}=x", "def {name, age = 1, \"k\": {v}} = x;\n"},
+		{"fun([a,b],c=a+b){c}", "fun([a, b], c = a + b) { c }\n"},
+		{"match x{[a,...b] if a>1=>a,{\"k\":-1}=>0,_=>1}", "match x { [a, ...b] if a > 1 => a, {\"k\": -1} => 0, _ => 1 }\n"},
+		{"def y = match x {\n1 => 2, // one\n\n_ => 3}", "def y = match x {\n\t1 => 2, // one\n\n\t_ => 3,\n};\n"},
+		{"throw  error(\"x\")", "throw error(\"x\");\n"},
+		{"try{f()}catch(e){g(e)}finally{h()}", "try { f() } catch (e) { g(e) } finally { h() }\n"},
+		{"def v=try{f()}catch{0}", "def v = try { f() } catch { 0 };\n"},
+		{
+			"try {\nf()\n} finally {\nh() }",
+			"try {\n\tf();\n} finally {\n\th();\n}\n",
+		},
+		{"a&&b||c", "a && b || c;\n"},
+		{"a&&(b||c)", "a && (b || c);\n"},
+		{"not(a==b)and(not c)", "not a == b and not c;\n"},
+		{"(not a)==b", "(not a) == b;\n"},
+		{"!(not a)", "!(not a);\n"},
+		{"a**(b**c)", "a ** b ** c;\n"},
+		{"(a**b)**c", "(a ** b) ** c;\n"},
+		{"(-a)**b", "(-a) ** b;\n"},
+		{"a<=b%c", "a <= b % c;\n"},
+		{"(a|b)&~c", "(a | b) & ~c;\n"},
+		{"def t=(1,2,)", "def t = (1, 2);\n"},
+		{"(a ,)", "(a,);\n"},
+		{"( )", "();\n"},
+		{"((a))", "a;\n"},
+		{"((1 < 2) == true)", "1 < 2 == true;\n"},
+		{"add(a,b,  1)", "add(a, b, 1);\n"},
+		{"[1,2 ,3]", "[1, 2, 3];\n"},
+		{`{"a":1,"b":2}`, "{\"a\": 1, \"b\": 2};\n"},
+		{"{}", "{};\n"},
+		{"#{1,2 ,3}", "#{1, 2, 3};\n"},
+		{"#{ }", "#{};\n"},
+		{"x in(a|b)", "x in a | b;\n"},
+		{"(x  not   in xs)==false", "x not in xs == false;\n"},
+		{"not(x in xs)", "not x in xs;\n"},
+		{"(a<b) in xs", "a < b in xs;\n"},
+		{"(a == b) in xs", "(a == b) in xs;\n"},
+		{"fun(x,y){x+y}", "fun(x, y) { x + y }\n"},
+		{"fun(){}", "fun() {}\n"},
+		{"if(x>1){y}else{z}", "if (x > 1) { y } else { z }\n"},
+		{
+			"def f = fun(x) {\ndef y = x*2;\n\n\nret y\n}",
+			"def f = fun(x) {\n\tdef y = x * 2;\n\n\tret y;\n};\n",
+		},
+		{
+			"if (x) {\n1 }\n(2)",
+			"if (x) {\n\t1;\n}(2);\n",
+		},
+		{
+			"if (x) {\n1 };\n(2)",
+			"if (x) {\n\t1;\n};\n2;\n",
+		},
+		{
+			"def h = {\"a\": 1,\n\"b\": 2}",
+			"def h = {\n\t\"a\": 1,\n\t\"b\": 2,\n};\n",
+		},
+		{
+			"def a = [1,\n2]",
+			"def a = [\n\t1,\n\t2\n];\n",
+		},
+	}
+
+	for _, tt := range tests {
+		out, err := Source([]byte(tt.input))
+		if err != nil {
+			t.Errorf("Source(%q) returned error: %s", tt.input, err)
+			continue
+		}
+
+		if string(out) != tt.expected {
+			t.Errorf("Source(%q) wrong. expected=%q, got=%q", tt.input, tt.expected, out)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// header
+
+def add = fun(x, y) {x + y};   // trailing
+def f = fun(a) {
+  // leading
+  ret a;
+  // last in block
+};
+def h = {
+	"a": 1, // one
+	// two
+	"b": 2
+};
+// end of file
+`
+	expected := `// header
+
+def add = fun(x, y) { x + y }; // trailing
+def f = fun(a) {
+	// leading
+	ret a;
+	// last in block
+};
+def h = {
+	"a": 1, // one
+	// two
+	"b": 2,
+};
+// end of file
+`
+
+	out, err := Source([]byte(input))
+	if err != nil {
+		t.Fatalf("Source returned error: %s", err)
+	}
+
+	if string(out) != expected {
+		t.Errorf("wrong output.\nexpected:\n%s\ngot:\n%s", expected, out)
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	inputs := []string{
+		`def add = fun(x,y){x+y}; // adds
+def f = fun(a) {
+  def b = (a*2)+1;
+  if(b>3){ret b;}else{ret -(a + 1);}
+};
+print(add(1, 2) * (3 - -4), {"a": [1, 2]}["a"][0]);`,
+		"// only a comment\n",
+		"",
+		"if (x) {\n} else {\n// nothing\n}",
+	}
+
+	for _, input := range inputs {
+		first, err := Source([]byte(input))
+		if err != nil {
+			t.Fatalf("Source(%q) returned error: %s", input, err)
+		}
+
+		second, err := Source(first)
+		if err != nil {
+			t.Fatalf("Source(%q) returned error: %s", first, err)
+		}
+
+		if string(first) != string(second) {
+			t.Errorf("formatting is not idempotent.\nfirst:\n%s\nsecond:\n%s", first, second)
+		}
+
+		if parse(t, input) != parse(t, string(first)) {
+			t.Errorf("formatting changed the program.\nbefore: %s\nafter: %s", parse(t, input), parse(t, string(first)))
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	if _, err := Source([]byte("def = 5;")); err == nil {
+		t.Errorf("expected an error for invalid input")
+	}
+}
+
+func parse(t *testing.T, input string) string {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program.String()
+}
@@ -1,34 +1,67 @@
 package lexer
 
-import "coff-src/src/coff/token"
+import (
+	"coff-src/src/coff/token"
+	"unicode"
+	"unicode/utf8"
+)
 
 type Lexer struct {
 	input		string
 	pos			int
 	readPos 	int
-	currChar	byte
+	currChar	rune
+
+	line		int
+	column		int
+	comments	[]token.Token
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
+// Comments returns the comments skipped so far, in source order.
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
 func (l *Lexer) readChar() {
+	if l.currChar == '\n' {
+		l.line += 1
+		l.column = 0
+	}
+	l.column += 1
+
+	width := 0
 	if l.readPos >= len(l.input) {
 		l.currChar = 0 // ASCII "NUL"
 	} else {
-		l.currChar = l.input[l.readPos]
+		l.currChar, width = utf8.DecodeRuneInString(l.input[l.readPos:])
 	}
 	l.pos = l.readPos
-	l.readPos += 1
+	l.readPos += width
 }
 
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
-
 	l.skipWhitespace()
+	for l.currChar == '/' && l.peekChar() == '/' {
+		l.comments = append(l.comments, l.readComment())
+		l.skipWhitespace()
+	}
+
+	line, column := l.line, l.column
+	tok := l.readToken()
+	tok.Line = line
+	tok.Column = column
+
+	return tok
+}
+
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
 
 	switch l.currChar {
 	case '=':
@@ -36,6 +69,9 @@ func (l *Lexer) NextToken() token.Token {
 			currChar := l.currChar
 			l.readChar()
 			tok = token.Token{Type: token.EQ, Literal: string(currChar) + string(l.currChar)}
+		} else if l.peekChar() == '>' {
+			l.readChar()
+			tok = token.Token{Type: token.ARROW, Literal: "=>"}
 		} else {
 			tok = newToken(token.ASSIGN, l.currChar)
 		}
@@ -43,6 +79,14 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.SEMICOLON, l.currChar)
 	case ':':
 		tok = newToken(token.COLON, l.currChar)
+	case '.':
+		if l.peekChar() == '.' && l.readPos+1 < len(l.input) && l.input[l.readPos+1] == '.' {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else {
+			tok = newToken(token.DOT, l.currChar)
+		}
 	case '(':
 		tok = newToken(token.LPAR, l.currChar)
 	case ')':
@@ -62,7 +106,18 @@ func (l *Lexer) NextToken() token.Token {
 	case '-':
 		tok = newToken(token.MINUS, l.currChar)
 	case '*':
-		tok = newToken(token.MULT, l.currChar)
+		if l.peekChar() == '*' {
+			l.readChar()
+			tok = token.Token{Type: token.POW, Literal: "**"}
+		} else {
+			tok = newToken(token.MULT, l.currChar)
+		}
+	case '%':
+		tok = newToken(token.MOD, l.currChar)
+	case '^':
+		tok = newToken(token.BIT_XOR, l.currChar)
+	case '~':
+		tok = newToken(token.BIT_NOT, l.currChar)
 	case '/':
 		tok = newToken(token.DIV, l.currChar)
 	case '#':
@@ -75,10 +130,40 @@ func (l *Lexer) NextToken() token.Token {
 		} else {
 			tok = newToken(token.FAC, l.currChar)
 		}
+	case '&':
+		if l.peekChar() == '&' {
+			l.readChar()
+			tok = token.Token{Type: token.AND, Literal: "&&"}
+		} else {
+			tok = newToken(token.BIT_AND, l.currChar)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			l.readChar()
+			tok = token.Token{Type: token.OR, Literal: "||"}
+		} else {
+			tok = newToken(token.BIT_OR, l.currChar)
+		}
 	case '<':
-		tok = newToken(token.LT, l.currChar)
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.LT_EQ, Literal: "<="}
+		} else if l.peekChar() == '<' {
+			l.readChar()
+			tok = token.Token{Type: token.SHL, Literal: "<<"}
+		} else {
+			tok = newToken(token.LT, l.currChar)
+		}
 	case '>':
-		tok = newToken(token.GT, l.currChar)
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.GT_EQ, Literal: ">="}
+		} else if l.peekChar() == '>' {
+			l.readChar()
+			tok = token.Token{Type: token.SHR, Literal: ">>"}
+		} else {
+			tok = newToken(token.GT, l.currChar)
+		}
 	case '"':
 		tok.Type = token.STR
 		tok.Literal = l.readStr()
@@ -103,11 +188,12 @@ func (l *Lexer) NextToken() token.Token {
 	return tok
 }
 
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
 	if l.readPos >= len(l.input) {
 		return 0
 	} else {
-		return l.input[l.readPos]
+		char, _ := utf8.DecodeRuneInString(l.input[l.readPos:])
+		return char
 	}
 }
 
@@ -129,6 +215,16 @@ func (l *Lexer) readStr() string {
 	return l.input[position:l.pos]
 }
 
+func (l *Lexer) readComment() token.Token {
+	tok := token.Token{Type: token.COMMENT, Line: l.line, Column: l.column}
+	pos := l.pos
+	for l.currChar != '\n' && l.currChar != 0 {
+		l.readChar()
+	}
+	tok.Literal = l.input[pos:l.pos]
+	return tok
+}
+
 func (l *Lexer) readNum() string {
 	pos := l.pos
 	for isDigit(l.currChar) {
@@ -145,14 +241,16 @@ func (l *Lexer) readId() string {
 	return l.input[pos:l.pos]
 }
 
-func isDigit(char byte) bool {
+func isDigit(char rune) bool {
 	return '0' <= char && char <= '9'
 }
 
-func isLetter(char byte) bool {
-	return 'a' <= char && char <= 'z' || 'A' <= char && char <= 'Z' || char == '_'
+// isLetter reports whether char can appear in an identifier: any Unicode
+// letter or '_'.
+func isLetter(char rune) bool {
+	return unicode.IsLetter(char) || char == '_'
 }
 
-func newToken(tokenType token.TokenType, char byte) token.Token {
+func newToken(tokenType token.TokenType, char rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(char)}
 }
\ No newline at end of file
@@ -18,6 +18,11 @@ func TestNextToken(t *testing.T) {
 	"foo bar"
 	[1, 2];
 	{"foo": "bar"}
+	match x { [h, ...t] => a.b }
+	a && b || not c and d or e
+	<= >= % ** & | ^ ~ << >> * < >
+	#{1} in s
+	a div b mod c
 	`
 
 	tests := []struct {
@@ -73,6 +78,54 @@ func TestNextToken(t *testing.T) {
 		{token.COLON, ":"},
 		{token.STR, "bar"},
 		{token.RBRA, "}"},
+		{token.MATCH, "match"},
+		{token.ID, "x"},
+		{token.LBRA, "{"},
+		{token.LBRACK, "["},
+		{token.ID, "h"},
+		{token.COMMA, ","},
+		{token.ELLIPSIS, "..."},
+		{token.ID, "t"},
+		{token.RBRACK, "]"},
+		{token.ARROW, "=>"},
+		{token.ID, "a"},
+		{token.DOT, "."},
+		{token.ID, "b"},
+		{token.RBRA, "}"},
+		{token.ID, "a"},
+		{token.AND, "&&"},
+		{token.ID, "b"},
+		{token.OR, "||"},
+		{token.NOT, "not"},
+		{token.ID, "c"},
+		{token.AND, "and"},
+		{token.ID, "d"},
+		{token.OR, "or"},
+		{token.ID, "e"},
+		{token.LT_EQ, "<="},
+		{token.GT_EQ, ">="},
+		{token.MOD, "%"},
+		{token.POW, "**"},
+		{token.BIT_AND, "&"},
+		{token.BIT_OR, "|"},
+		{token.BIT_XOR, "^"},
+		{token.BIT_NOT, "~"},
+		{token.SHL, "<<"},
+		{token.SHR, ">>"},
+		{token.MULT, "*"},
+		{token.LT, "<"},
+		{token.GT, ">"},
+		{token.HASH, "#"},
+		{token.LBRA, "{"},
+		{token.INT, "1"},
+		{token.RBRA, "}"},
+		{token.IN, "in"},
+		{token.ID, "s"},
+		{token.ID, "a"},
+		{token.FLOOR_DIV, "div"},
+		{token.ID, "b"},
+		{token.FLOOR_MOD, "mod"},
+		{token.ID, "c"},
 		{token.EOF, ""},
 	}
 
@@ -91,4 +144,93 @@ func TestNextToken(t *testing.T) {
 					 i, tt.expectedLiteral, tok.Literal)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestPositionsAndComments(t *testing.T) {
+	input := `def x = 1; // one
+// two
+  x`
+
+	tests := []struct {
+		expectedType	token.TokenType
+		expectedLine	int
+		expectedColumn	int
+	} {
+		{token.DEF, 1, 1},
+		{token.ID, 1, 5},
+		{token.ASSIGN, 1, 7},
+		{token.INT, 1, 9},
+		{token.SEMICOLON, 1, 10},
+		{token.ID, 3, 3},
+		{token.EOF, 3, 4},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - incorrect token type. expected=%q, got=%q",
+					 i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - incorrect position. expected=%d:%d, got=%d:%d",
+					 i, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+
+	comments := l.Comments()
+	if len(comments) != 2 {
+		t.Fatalf("wrong number of comments. got=%d", len(comments))
+	}
+
+	if comments[0].Literal != "// one" || comments[0].Line != 1 || comments[0].Column != 12 {
+		t.Errorf("wrong first comment. got=%+v", comments[0])
+	}
+
+	if comments[1].Literal != "// two" || comments[1].Line != 2 || comments[1].Column != 1 {
+		t.Errorf("wrong second comment. got=%+v", comments[1])
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	input := `def café = "naïve ☕"; größe + π_2 é`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedColumn  int
+	}{
+		{token.DEF, "def", 1},
+		{token.ID, "café", 5},
+		{token.ASSIGN, "=", 10},
+		{token.STR, "naïve ☕", 12},
+		{token.SEMICOLON, ";", 21},
+		{token.ID, "größe", 23},
+		{token.PLUS, "+", 29},
+		{token.ID, "π_", 31},
+		{token.INT, "2", 33},
+		{token.ID, "é", 35},
+		{token.EOF, "", 36},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%s %q, got=%s %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+		if tok.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - wrong column. expected=%d, got=%d", i, tt.expectedColumn, tok.Column)
+		}
+	}
+
+	if tok := New("€").NextToken(); tok.Type != token.INVALID || tok.Literal != "€" {
+		t.Errorf("wrong token for a symbol. got=%s %q", tok.Type, tok.Literal)
+	}
+}
@@ -0,0 +1,478 @@
+// Package lint reports likely mistakes in CoffLang programs without
+// running them.
+//
+// A finding can be silenced with a comment of the form
+//
+//	// lint:ignore rule[,rule...]
+//
+// placed at the end of the offending line or on the line above it.
+// Leaving out the rule list silences every rule.
+package lint
+
+import (
+	"coff-src/src/coff/ast"
+	"coff-src/src/coff/eval"
+	"coff-src/src/coff/format"
+	"coff-src/src/coff/lexer"
+	"coff-src/src/coff/object"
+	"coff-src/src/coff/parser"
+	"coff-src/src/coff/token"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Rule identifiers.
+const (
+	SYNTAX           = "syntax"
+	UNUSED_DEF       = "unused-def"
+	UNUSED_PARAM     = "unused-param"
+	SHADOW           = "shadow"
+	UNREACHABLE      = "unreachable"
+	ARG_COUNT        = "arg-count"
+	CONSTANT_COMPARE = "constant-compare"
+	IF_VALUE         = "if-value"
+)
+
+// Issue is a single finding.
+type Issue struct {
+	Line    int
+	Column  int
+	Rule    string
+	Message string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%d:%d: %s (%s)", i.Line, i.Column, i.Message, i.Rule)
+}
+
+// Source parses and checks src. Syntax errors are reported as issues
+// with rule SYNTAX; the other rules only run on programs that parse.
+func Source(src []byte) []Issue {
+	l := lexer.New(string(src))
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	issues := []Issue{}
+	for _, e := range p.ParseErrors() {
+		issues = append(issues, Issue{
+			Line:    e.Token.Line,
+			Column:  e.Token.Column,
+			Rule:    SYNTAX,
+			Message: e.Message,
+		})
+	}
+	if len(issues) != 0 {
+		return issues
+	}
+
+	return suppress(Program(program), l.Comments(), strings.Split(string(src), "\n"))
+}
+
+// Program checks an already parsed program.
+func Program(program *ast.Program) []Issue {
+	c := &checker{}
+	top := &scope{names: map[string]*binding{}}
+	c.statements(program.Statements, top)
+	c.close(top)
+
+	sort.SliceStable(c.issues, func(i, j int) bool {
+		a, b := c.issues[i], c.issues[j]
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+
+	return c.issues
+}
+
+type binding struct {
+	name  string
+	tok   token.Token
+	param bool
+	used  bool
+	fun   *ast.FunctionLiteral // the value, if the binding is a def of a function literal
+}
+
+// scope mirrors an object.Env: the program, every function call and
+// every match arm get one, while if-blocks share the scope they appear
+// in.
+type scope struct {
+	outer   *scope
+	names   map[string]*binding
+	order   []*binding
+	pending []*ast.FunctionLiteral
+}
+
+func (s *scope) lookup(name string) *binding {
+	for ; s != nil; s = s.outer {
+		if b, ok := s.names[name]; ok {
+			return b
+		}
+	}
+	return nil
+}
+
+type checker struct {
+	issues []Issue
+}
+
+func (c *checker) report(tok token.Token, rule string, msg string, a ...interface{}) {
+	c.issues = append(c.issues, Issue{
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Rule:    rule,
+		Message: fmt.Sprintf(msg, a...),
+	})
+}
+
+// close finishes a scope: function bodies are checked only once every
+// binding of the enclosing scope is known, since a body runs after the
+// definitions around it and may refer to any of them, itself included.
+func (c *checker) close(s *scope) {
+	for len(s.pending) > 0 {
+		fn := s.pending[0]
+		s.pending = s.pending[1:]
+		c.function(fn, s)
+	}
+
+	for _, b := range s.order {
+		if b.used || strings.HasPrefix(b.name, "_") {
+			continue
+		}
+		if b.param {
+			c.report(b.tok, UNUSED_PARAM, "parameter %s is never used", b.name)
+		} else {
+			c.report(b.tok, UNUSED_DEF, "%s is defined but never used", b.name)
+		}
+	}
+}
+
+func (c *checker) function(fn *ast.FunctionLiteral, outer *scope) {
+	s := &scope{outer: outer, names: map[string]*binding{}}
+	for _, p := range fn.Parameters {
+		for _, id := range ast.Bindings(p) {
+			c.bind(s, &binding{name: id.Value, tok: id.Token, param: true})
+		}
+		for _, d := range ast.Defaults(p) {
+			c.value(d, s)
+		}
+	}
+
+	c.statements(fn.Body.Statements, s)
+	c.close(s)
+}
+
+func (c *checker) bind(s *scope, b *binding) {
+	if s.outer != nil && s.outer.lookup(b.name) != nil {
+		c.report(b.tok, SHADOW, "%s shadows a definition in an outer scope", b.name)
+	} else if isBuiltin(b.name) {
+		c.report(b.tok, SHADOW, "%s shadows the builtin function %s", b.name, b.name)
+	}
+
+	s.names[b.name] = b
+	s.order = append(s.order, b)
+}
+
+func (c *checker) statements(stmts []ast.Statement, s *scope) {
+	returned, reported := false, false
+	exit := "ret"
+	for _, stmt := range stmts {
+		if returned && !reported {
+			c.report(ast.Start(stmt), UNREACHABLE, "unreachable code after %s", exit)
+			reported = true
+		}
+		if c.statement(stmt, s) && !returned {
+			returned = true
+			if _, ok := stmt.(*ast.ThrowStatement); ok {
+				exit = "throw"
+			}
+		}
+	}
+}
+
+// statement checks stmt and reports whether it always returns.
+func (c *checker) statement(stmt ast.Statement, s *scope) bool {
+	switch stmt := stmt.(type) {
+	case *ast.DefStatement:
+		c.value(stmt.Value, s)
+		if stmt.Pattern != nil {
+			for _, id := range ast.Bindings(stmt.Pattern) {
+				c.bind(s, &binding{name: id.Value, tok: id.Token, used: stmt.Exported()})
+			}
+			for _, d := range ast.Defaults(stmt.Pattern) {
+				c.value(d, s)
+			}
+			break
+		}
+		b := &binding{name: stmt.Name.Value, tok: stmt.Name.Token, used: stmt.Exported()}
+		if fn, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+			b.fun = fn
+		}
+		c.bind(s, b)
+	case *ast.AssignStatement:
+		c.value(stmt.Target, s)
+		c.value(stmt.Value, s)
+	case *ast.StructStatement:
+		c.bind(s, &binding{name: stmt.Name.Value, tok: stmt.Name.Token, used: stmt.Exported()})
+	case *ast.ClassStatement:
+		if stmt.Super != nil {
+			c.value(stmt.Super, s)
+		}
+		c.bind(s, &binding{name: stmt.Name.Value, tok: stmt.Name.Token, used: stmt.Exported()})
+		s.pending = append(s.pending, stmt.Methods...)
+	case *ast.ImportStatement:
+		c.bind(s, &binding{name: stmt.Name.Value, tok: stmt.Name.Token})
+	case *ast.RetStatement:
+		c.value(stmt.RetVal, s)
+		return true
+	case *ast.ThrowStatement:
+		c.value(stmt.Value, s)
+		return true
+	case *ast.ExpressionStatement:
+		c.expression(stmt.Expression, s)
+		if ie, ok := stmt.Expression.(*ast.IfExpression); ok {
+			return returns(ie.Consequence) && ie.Alternative != nil && returns(ie.Alternative)
+		}
+	case *ast.BlockStatement:
+		c.statements(stmt.Statements, s)
+		return returns(stmt)
+	}
+
+	return false
+}
+
+// returns reports whether every path through block ends in a ret.
+func returns(block *ast.BlockStatement) bool {
+	for _, stmt := range block.Statements {
+		switch stmt := stmt.(type) {
+		case *ast.RetStatement, *ast.ThrowStatement:
+			return true
+		case *ast.ExpressionStatement:
+			ie, ok := stmt.Expression.(*ast.IfExpression)
+			if ok && ie.Alternative != nil && returns(ie.Consequence) && returns(ie.Alternative) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// value checks an expression whose result is used.
+func (c *checker) value(e ast.Expression, s *scope) {
+	if ie, ok := e.(*ast.IfExpression); ok && ie.Alternative == nil {
+		c.report(ie.Token, IF_VALUE, "if without else used as a value is null when the condition is false")
+	}
+	c.expression(e, s)
+}
+
+func (c *checker) expression(e ast.Expression, s *scope) {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		if b := s.lookup(e.Value); b != nil {
+			b.used = true
+		}
+	case *ast.PrefixExpression:
+		c.value(e.Right, s)
+	case *ast.InfixExpression:
+		c.value(e.Left, s)
+		c.value(e.Right, s)
+		c.comparison(e)
+	case *ast.IfExpression:
+		c.value(e.Condition, s)
+		c.statements(e.Consequence.Statements, s)
+		if e.Alternative != nil {
+			c.statements(e.Alternative.Statements, s)
+		}
+	case *ast.FunctionLiteral:
+		s.pending = append(s.pending, e)
+	case *ast.CallExpression:
+		c.value(e.Function, s)
+		for _, a := range e.Arguments {
+			c.value(a, s)
+		}
+		c.call(e, s)
+	case *ast.ArrLiteral:
+		for _, el := range e.Elements {
+			c.value(el, s)
+		}
+	case *ast.TupleLiteral:
+		for _, el := range e.Elements {
+			c.value(el, s)
+		}
+	case *ast.SetLiteral:
+		for _, el := range e.Elements {
+			c.value(el, s)
+		}
+	case *ast.IdxExpression:
+		c.value(e.Left, s)
+		c.value(e.Index, s)
+	case *ast.SliceExpression:
+		c.value(e.Left, s)
+		for _, bound := range []ast.Expression{e.Low, e.High, e.Step} {
+			if bound != nil {
+				c.value(bound, s)
+			}
+		}
+	case *ast.MemberExpression:
+		c.value(e.Object, s)
+	case *ast.HashLiteral:
+		for _, k := range e.Keys {
+			c.value(k, s)
+			c.value(e.Pairs[k], s)
+		}
+	case *ast.MatchExpression:
+		c.value(e.Subject, s)
+		for _, arm := range e.Arms {
+			c.arm(arm, s)
+		}
+	case *ast.TryExpression:
+		c.statements(e.Body.Statements, s)
+		if e.Catch != nil {
+			c.catch(e, s)
+		}
+		if e.Finally != nil {
+			c.statements(e.Finally.Statements, s)
+		}
+	}
+}
+
+// catch checks a catch block in a scope of its own holding the caught
+// error.
+func (c *checker) catch(te *ast.TryExpression, outer *scope) {
+	s := &scope{outer: outer, names: map[string]*binding{}}
+	if te.Param != nil {
+		c.bind(s, &binding{name: te.Param.Value, tok: te.Param.Token})
+	}
+
+	c.statements(te.Catch.Statements, s)
+	c.close(s)
+}
+
+// arm checks a match arm in a scope of its own holding the names its
+// pattern binds.
+func (c *checker) arm(arm *ast.MatchArm, outer *scope) {
+	s := &scope{outer: outer, names: map[string]*binding{}}
+	for _, id := range ast.Bindings(arm.Pattern) {
+		c.bind(s, &binding{name: id.Value, tok: id.Token})
+	}
+	for _, d := range ast.Defaults(arm.Pattern) {
+		c.value(d, s)
+	}
+
+	if arm.Guard != nil {
+		c.value(arm.Guard, s)
+	}
+	c.value(arm.Body, s)
+	c.close(s)
+}
+
+func (c *checker) call(ce *ast.CallExpression, s *scope) {
+	id, ok := ce.Function.(*ast.Identifier)
+	if !ok {
+		return
+	}
+
+	b := s.lookup(id.Value)
+	if b == nil || b.fun == nil {
+		return
+	}
+
+	min, max := ast.Required(b.fun.Parameters), len(b.fun.Parameters)
+	if got := len(ce.Arguments); got < min || got > max {
+		want := fmt.Sprint(max)
+		if min != max {
+			want = fmt.Sprintf("%d to %d", min, max)
+		}
+		c.report(ast.Start(ce), ARG_COUNT, "%s called with %d arguments, want %s", id.Value, got, want)
+	}
+}
+
+var comparisons = map[string]bool{"==": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true}
+
+// comparison reports comparisons whose outcome does not depend on any
+// variable: those between two literals, and those of a name with itself.
+func (c *checker) comparison(ie *ast.InfixExpression) {
+	if !comparisons[ie.Operator] {
+		return
+	}
+
+	var result object.Object
+	if isLiteral(ie.Left) && isLiteral(ie.Right) {
+		result = eval.Eval(ie, object.NewEnv())
+	} else if left, ok := ie.Left.(*ast.Identifier); ok {
+		if right, ok := ie.Right.(*ast.Identifier); ok && left.Value == right.Value {
+			result = &object.Bool{Value: ie.Operator == "==" || ie.Operator == "<=" || ie.Operator == ">="}
+		}
+	}
+
+	if b, ok := result.(*object.Bool); ok {
+		c.report(ast.Start(ie), CONSTANT_COMPARE, "comparison %s is always %t", format.Node(ie), b.Value)
+	}
+}
+
+func isLiteral(e ast.Expression) bool {
+	switch e.(type) {
+	case *ast.IntLiteral, *ast.StrLiteral, *ast.Boolean:
+		return true
+	}
+	return false
+}
+
+func isBuiltin(name string) bool {
+	for _, b := range eval.Builtins() {
+		if b == name {
+			return true
+		}
+	}
+	return false
+}
+
+// suppress drops the issues silenced by lint:ignore comments. A comment
+// after code applies to its own line, one on a line by itself to the
+// line below.
+func suppress(issues []Issue, comments []token.Token, lines []string) []Issue {
+	ignored := map[int][]string{}
+	for _, c := range comments {
+		text := strings.TrimSpace(strings.TrimPrefix(c.Literal, "//"))
+		if !strings.HasPrefix(text, "lint:ignore") {
+			continue
+		}
+
+		rules := []string{}
+		for _, r := range strings.Split(strings.TrimPrefix(text, "lint:ignore"), ",") {
+			if r = strings.TrimSpace(r); r != "" {
+				rules = append(rules, r)
+			}
+		}
+		if len(rules) == 0 {
+			rules = []string{"*"}
+		}
+
+		// Columns count code points, so slice runes rather than bytes.
+		line, before := c.Line, []rune(lines[c.Line-1])
+		if c.Column-1 < len(before) {
+			before = before[:c.Column-1]
+		}
+		if strings.TrimSpace(string(before)) == "" {
+			line++
+		}
+		ignored[line] = append(ignored[line], rules...)
+	}
+
+	kept := []Issue{}
+	for _, i := range issues {
+		if !matches(ignored[i.Line], i.Rule) {
+			kept = append(kept, i)
+		}
+	}
+
+	return kept
+}
+
+func matches(rules []string, rule string) bool {
+	for _, r := range rules {
+		if r == "*" || r == rule {
+			return true
+		}
+	}
+	return false
+}
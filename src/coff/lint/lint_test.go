@@ -0,0 +1,157 @@
+package lint
+
+import (
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			"def unused = 1;",
+			[]string{"1:5: unused is defined but never used (unused-def)"},
+		},
+		{
+			"def _ignored = 1;",
+			[]string{},
+		},
+		{
+			"export def exported = 1;",
+			[]string{},
+		},
+		{
+			`import "lib.coff" as lib; import "other.coff" as other; lib.x;`,
+			[]string{"1:50: other is defined but never used (unused-def)"},
+		},
+		{
+			"def f = fun(a, b) { a }; f(1, 2);",
+			[]string{"1:16: parameter b is never used (unused-param)"},
+		},
+		{
+			"def len = 1; print(len);",
+			[]string{"1:5: len shadows the builtin function len (shadow)"},
+		},
+		{
+			"def x = 1; def f = fun(x) { x }; f(x);",
+			[]string{"1:24: x shadows a definition in an outer scope (shadow)"},
+		},
+		{
+			"def f = fun() { ret 1; print(2); }; f();",
+			[]string{"1:24: unreachable code after ret (unreachable)"},
+		},
+		{
+			"def f = fun(x) { if (x) { ret 1; } else { ret 2; } x }; f(1);",
+			[]string{"1:52: unreachable code after ret (unreachable)"},
+		},
+		{
+			`def f = fun() { throw "x"; print(2); }; f();`,
+			[]string{"1:28: unreachable code after throw (unreachable)"},
+		},
+		{
+			"try { 1 } catch (e) { 2 }",
+			[]string{"1:18: e is defined but never used (unused-def)"},
+		},
+		{
+			"try { 1 } catch (e) { print(e) } finally { 2 }",
+			[]string{},
+		},
+		{
+			"def add = fun(a, b) { a + b }; add(1);",
+			[]string{"1:32: add called with 1 arguments, want 2 (arg-count)"},
+		},
+		{
+			"def f = fun(n) { if (n > 0) { f(n - 1) } else { 0 } }; f(1, 2);",
+			[]string{"1:56: f called with 2 arguments, want 1 (arg-count)"},
+		},
+		{
+			"if (1 < 2) { 3 }",
+			[]string{"1:5: comparison 1 < 2 is always true (constant-compare)"},
+		},
+		{
+			"def x = 1; x >= x;",
+			[]string{"1:12: comparison x >= x is always true (constant-compare)"},
+		},
+		{
+			`"a" <= "b";`,
+			[]string{"1:1: comparison \"a\" <= \"b\" is always true (constant-compare)"},
+		},
+		{
+			"def x = 1; x != x;",
+			[]string{"1:12: comparison x != x is always false (constant-compare)"},
+		},
+		{
+			"def x = 1; def y = if (x) { 1 }; print(y);",
+			[]string{"1:20: if without else used as a value is null when the condition is false (if-value)"},
+		},
+		{
+			"def x = 1; def y = if (x) { 1 } else { 2 }; print(y); if (y) { print(x) }",
+			[]string{},
+		},
+		{
+			"def f = fun() { g() }; def g = fun() { f() }; f();",
+			[]string{},
+		},
+		{
+			"class A { f(x) { self.y } } class B < A { g() { 1 } } class C {}",
+			[]string{
+				"1:13: parameter x is never used (unused-param)",
+				"1:35: B is defined but never used (unused-def)",
+				"1:61: C is defined but never used (unused-def)",
+			},
+		},
+		{
+			"def x = 1; match [x] { [x, ...more] if x => 1, _ => 2 }",
+			[]string{
+				"1:25: x shadows a definition in an outer scope (shadow)",
+				"1:31: more is defined but never used (unused-def)",
+			},
+		},
+		{
+			"def f = fun(a, b = 1) { a + b }; f(); f(1); f(1, 2); f(1, 2, 3);",
+			[]string{
+				"1:34: f called with 0 arguments, want 1 to 2 (arg-count)",
+				"1:54: f called with 3 arguments, want 1 to 2 (arg-count)",
+			},
+		},
+		{
+			"def [a, {b, c = a}] = x; def f = fun([p, q]) { p }; f(a);",
+			[]string{
+				"1:10: b is defined but never used (unused-def)",
+				"1:13: c is defined but never used (unused-def)",
+				"1:42: parameter q is never used (unused-param)",
+			},
+		},
+		{
+			"def = 1;",
+			[]string{
+				"1:5: expected next token to be ID but got = instead (syntax)",
+				"1:5: no prefix parse function for = found (syntax)",
+			},
+		},
+		{
+			"def a = 1; // lint:ignore unused-def\n// lint:ignore\ndef b = 1;\ndef c = 1; // lint:ignore shadow",
+			[]string{"4:5: c is defined but never used (unused-def)"},
+		},
+		{
+			"def s = \"日本語\"; // lint:ignore unused-def\ndef t = \"😀\";\n// lint:ignore\ndef u = 1;",
+			[]string{"2:5: t is defined but never used (unused-def)"},
+		},
+	}
+
+	for _, tt := range tests {
+		issues := Source([]byte(tt.input))
+
+		if len(issues) != len(tt.expected) {
+			t.Errorf("wrong number of issues for %q. expected=%q, got=%q", tt.input, tt.expected, issues)
+			continue
+		}
+
+		for i, issue := range issues {
+			if issue.String() != tt.expected[i] {
+				t.Errorf("wrong issue for %q. expected=%q, got=%q", tt.input, tt.expected[i], issue.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package lsp
+
+import (
+	"coff-src/src/coff/ast"
+	"coff-src/src/coff/lexer"
+	"coff-src/src/coff/parser"
+	"coff-src/src/coff/token"
+	"strings"
+	"unicode/utf8"
+)
+
+// symbol is a name introduced by a def statement or a function
+// parameter.
+type symbol struct {
+	name      string
+	tok       token.Token // the identifier that declares it
+	param     bool
+	value     ast.Expression       // the defined value; nil for parameters and imports
+	module    string               // the path of an imported module
+	structure *ast.StructStatement // the declaration of a struct
+	class     *ast.ClassStatement  // the declaration of a class
+}
+
+// scope mirrors an object.Env at run time: the program, every function
+// body and every match arm get one, if-blocks share the scope around
+// them.
+type scope struct {
+	outer    *scope
+	start    token.Token // the fun keyword or the arm's pattern; zero for the program
+	end      token.Token // the closing brace of the body, or the token after an arm
+	symbols  []*symbol
+	names    map[string]*symbol
+	pending  []*ast.FunctionLiteral
+	children []*scope
+}
+
+func (s *scope) lookup(name string) *symbol {
+	for ; s != nil; s = s.outer {
+		if sym, ok := s.names[name]; ok {
+			return sym
+		}
+	}
+	return nil
+}
+
+func (s *scope) define(sym *symbol) {
+	s.symbols = append(s.symbols, sym)
+	s.names[sym.name] = sym
+}
+
+func (s *scope) contains(line, column int) bool {
+	if s.outer == nil {
+		return true
+	}
+	return !before(line, column, s.start) && before(line, column, s.end)
+}
+
+// document is an open file together with what the server knows about
+// its names.
+type document struct {
+	text     string
+	lines    []string
+	program  *ast.Program
+	errors   []parser.ParseError
+	top      *scope
+	fnScopes map[*ast.FunctionLiteral]*scope
+	refs     map[*ast.Identifier]*symbol
+	idents   []*ast.Identifier
+}
+
+func newDocument(text string) *document {
+	p := parser.New(lexer.New(text))
+	d := &document{
+		text:     text,
+		lines:    strings.Split(text, "\n"),
+		program:  p.ParseProgram(),
+		errors:   p.ParseErrors(),
+		top:      &scope{names: map[string]*symbol{}},
+		fnScopes: map[*ast.FunctionLiteral]*scope{},
+		refs:     map[*ast.Identifier]*symbol{},
+	}
+
+	d.statements(d.program.Statements, d.top)
+	d.close(d.top)
+
+	return d
+}
+
+// close resolves the function bodies of a scope once all of its
+// bindings are known: a body runs after the definitions around it, so
+// it may refer to any of them.
+func (d *document) close(s *scope) {
+	for len(s.pending) > 0 {
+		fn := s.pending[0]
+		s.pending = s.pending[1:]
+
+		fs := &scope{outer: s, start: fn.Token, end: fn.Body.EndToken, names: map[string]*symbol{}}
+		s.children = append(s.children, fs)
+		d.fnScopes[fn] = fs
+
+		for _, p := range fn.Parameters {
+			d.pattern(fs, p, true)
+		}
+		d.statements(fn.Body.Statements, fs)
+		d.close(fs)
+	}
+}
+
+// pattern declares the names bound by p and indexes its default values.
+func (d *document) pattern(s *scope, p ast.Pattern, param bool) {
+	for _, id := range ast.Bindings(p) {
+		d.declare(s, id, &symbol{name: id.Value, tok: id.Token, param: param})
+	}
+	for _, e := range ast.Defaults(p) {
+		d.expression(e, s)
+	}
+}
+
+func (d *document) declare(s *scope, id *ast.Identifier, sym *symbol) {
+	s.define(sym)
+	d.refs[id] = sym
+	d.idents = append(d.idents, id)
+}
+
+func (d *document) statements(stmts []ast.Statement, s *scope) {
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *ast.DefStatement:
+			if stmt == nil {
+				continue
+			}
+			d.expression(stmt.Value, s)
+			if stmt.Pattern != nil {
+				d.pattern(s, stmt.Pattern, false)
+				continue
+			}
+			d.declare(s, stmt.Name, &symbol{name: stmt.Name.Value, tok: stmt.Name.Token, value: stmt.Value})
+		case *ast.ClassStatement:
+			if stmt.Super != nil {
+				d.expression(stmt.Super, s)
+			}
+			d.declare(s, stmt.Name, &symbol{name: stmt.Name.Value, tok: stmt.Name.Token, class: stmt})
+			s.pending = append(s.pending, stmt.Methods...)
+		case *ast.StructStatement:
+			d.declare(s, stmt.Name, &symbol{name: stmt.Name.Value, tok: stmt.Name.Token, structure: stmt})
+		case *ast.ImportStatement:
+			d.declare(s, stmt.Name, &symbol{name: stmt.Name.Value, tok: stmt.Name.Token, module: stmt.Path.Value})
+		case *ast.AssignStatement:
+			d.expression(stmt.Target, s)
+			d.expression(stmt.Value, s)
+		case *ast.RetStatement:
+			d.expression(stmt.RetVal, s)
+		case *ast.ThrowStatement:
+			d.expression(stmt.Value, s)
+		case *ast.ExpressionStatement:
+			d.expression(stmt.Expression, s)
+		}
+	}
+}
+
+func (d *document) expression(e ast.Expression, s *scope) {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		d.idents = append(d.idents, e)
+		if sym := s.lookup(e.Value); sym != nil {
+			d.refs[e] = sym
+		}
+	case *ast.FunctionLiteral:
+		s.pending = append(s.pending, e)
+	case *ast.MemberExpression:
+		d.expression(e.Object, s)
+	case *ast.MatchExpression:
+		d.expression(e.Subject, s)
+		for i, arm := range e.Arms {
+			end := e.EndToken
+			if i+1 < len(e.Arms) {
+				end = ast.Start(e.Arms[i+1].Pattern)
+			}
+			d.arm(arm, s, end)
+		}
+	case *ast.TryExpression:
+		d.statements(e.Body.Statements, s)
+		if e.Catch != nil {
+			d.catch(e, s)
+		}
+		if e.Finally != nil {
+			d.statements(e.Finally.Statements, s)
+		}
+	case *ast.IfExpression:
+		d.expression(e.Condition, s)
+		if e.Consequence != nil {
+			d.statements(e.Consequence.Statements, s)
+		}
+		if e.Alternative != nil {
+			d.statements(e.Alternative.Statements, s)
+		}
+	default:
+		if e == nil {
+			return
+		}
+		ast.Inspect(e, func(n ast.Node) bool {
+			if n == e {
+				return true
+			}
+			if child, ok := n.(ast.Expression); ok {
+				d.expression(child, s)
+			}
+			return false
+		})
+	}
+}
+
+// arm indexes a match arm, whose pattern binds names visible up to end.
+func (d *document) arm(arm *ast.MatchArm, outer *scope, end token.Token) {
+	s := &scope{outer: outer, start: ast.Start(arm.Pattern), end: end, names: map[string]*symbol{}}
+	outer.children = append(outer.children, s)
+
+	d.pattern(s, arm.Pattern, false)
+	d.expression(arm.Guard, s)
+	d.expression(arm.Body, s)
+	d.close(s)
+}
+
+// catch indexes a catch block, whose parameter is visible in the block
+// only.
+func (d *document) catch(te *ast.TryExpression, outer *scope) {
+	s := &scope{outer: outer, start: te.Catch.Token, end: te.Catch.EndToken, names: map[string]*symbol{}}
+	outer.children = append(outer.children, s)
+
+	if te.Param != nil {
+		d.declare(s, te.Param, &symbol{name: te.Param.Value, tok: te.Param.Token, param: true})
+	}
+	d.statements(te.Catch.Statements, s)
+	d.close(s)
+}
+
+// identAt returns the identifier covering the given 1-based position.
+func (d *document) identAt(line, column int) *ast.Identifier {
+	for _, id := range d.idents {
+		if id.Token.Line == line && id.Token.Column <= column && column <= id.Token.Column+utf8.RuneCountInString(id.Value) {
+			return id
+		}
+	}
+	return nil
+}
+
+// scopeAt returns the innermost scope containing the given position.
+func (d *document) scopeAt(line, column int) *scope {
+	s := d.top
+	for {
+		inner := (*scope)(nil)
+		for _, c := range s.children {
+			if c.contains(line, column) {
+				inner = c
+			}
+		}
+		if inner == nil {
+			return s
+		}
+		s = inner
+	}
+}
+
+// visible returns the symbols usable at the given position, innermost
+// first. Names of the innermost scope count only once defined; outer
+// names are visible in full since function bodies run later.
+func (d *document) visible(line, column int) []*symbol {
+	seen := map[string]bool{}
+	result := []*symbol{}
+
+	inner := d.scopeAt(line, column)
+	for s := inner; s != nil; s = s.outer {
+		for i := len(s.symbols) - 1; i >= 0; i-- {
+			sym := s.symbols[i]
+			if seen[sym.name] || s == inner && !sym.param && !before(sym.tok.Line, sym.tok.Column, token.Token{Line: line, Column: column}) {
+				continue
+			}
+			seen[sym.name] = true
+			result = append(result, sym)
+		}
+	}
+
+	return result
+}
+
+// kind describes what a symbol holds as far as can be told without
+// running the program.
+func (d *document) kind(sym *symbol) string {
+	if sym.param {
+		return "parameter"
+	}
+	if sym.module != "" {
+		return "module"
+	}
+	if sym.structure != nil {
+		return "struct"
+	}
+	if sym.class != nil {
+		return "class"
+	}
+	if _, ok := sym.value.(*ast.FunctionLiteral); ok {
+		return "function"
+	}
+
+	return "variable"
+}
+
+// valueKind guesses the type of an expression, or returns "".
+func (d *document) valueKind(e ast.Expression) string {
+	switch e := e.(type) {
+	case *ast.IntLiteral:
+		return "int"
+	case *ast.StrLiteral:
+		return "string"
+	case *ast.Boolean:
+		return "bool"
+	case *ast.ArrLiteral:
+		return "array"
+	case *ast.TupleLiteral:
+		return "tuple"
+	case *ast.HashLiteral:
+		return "hash"
+	case *ast.SetLiteral:
+		return "set"
+	case *ast.SliceExpression:
+		return d.valueKind(e.Left)
+	case *ast.FunctionLiteral:
+		return "function"
+	case *ast.PrefixExpression:
+		if e.Operator == "!" || e.Operator == "not" {
+			return "bool"
+		}
+		return d.valueKind(e.Right)
+	case *ast.InfixExpression:
+		switch e.Operator {
+		case "==", "!=", "<", ">", "<=", ">=", "is", "in", "not in":
+			return "bool"
+		case "&&", "||", "and", "or":
+			// The result is either operand.
+			if left := d.valueKind(e.Left); left == d.valueKind(e.Right) {
+				return left
+			}
+			return ""
+		}
+		return d.valueKind(e.Left)
+	case *ast.Identifier:
+		if sym, ok := d.refs[e]; ok && sym.value != nil {
+			return d.valueKind(sym.value)
+		}
+	}
+
+	return ""
+}
+
+// signature renders the parameter list of a function literal.
+func signature(name string, fn *ast.FunctionLiteral) string {
+	params := []string{}
+	for _, p := range fn.Parameters {
+		params = append(params, p.String())
+	}
+	return name + "(" + strings.Join(params, ", ") + ")"
+}
+
+// before reports whether the 1-based position line:column comes before
+// tok.
+func before(line, column int, tok token.Token) bool {
+	return line < tok.Line || line == tok.Line && column < tok.Column
+}
@@ -0,0 +1,442 @@
+// Package lsp implements a Language server Protocol server for
+// CoffLang. It speaks JSON-RPC over a pair of streams, normally the
+// standard input and output of `coff lsp`, and keeps every open file in
+// memory: edits are sent in full and re-analysed on each change.
+package lsp
+
+import (
+	"bufio"
+	"coff-src/src/coff/ast"
+	"coff-src/src/coff/eval"
+	"coff-src/src/coff/format"
+	"coff-src/src/coff/lint"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+type server struct {
+	in       *bufio.Reader
+	out      io.Writer
+	docs     map[string]*document
+	shutdown bool
+}
+
+// Serve answers requests read from in on out until the client sends
+// the exit notification or in is closed.
+func Serve(in io.Reader, out io.Writer) error {
+	s := &server{
+		in:   bufio.NewReader(in),
+		out:  out,
+		docs: map[string]*document{},
+	}
+
+	for {
+		req, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit requested before shutdown")
+			}
+			return nil
+		}
+
+		result, rerr := s.handle(req)
+		if req.ID == nil {
+			continue
+		}
+		if err := s.write(response{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rerr}); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *server) read() (*request, error) {
+	headers, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length: %s", err)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+
+	req := &request{}
+	if err := json.Unmarshal(body, req); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+func (s *server) write(msg interface{}) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func (s *server) handle(req *request) (interface{}, *responseError) {
+	switch req.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":           1, // full document on every change
+				"hoverProvider":              true,
+				"definitionProvider":         true,
+				"completionProvider":         map[string]interface{}{},
+				"documentSymbolProvider":     true,
+				"documentFormattingProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "coff"},
+		}, nil
+	case "initialized", "$/cancelRequest", "textDocument/didSave":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		params := DidOpenTextDocumentParams{}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &responseError{Code: invalidParams, Message: err.Error()}
+		}
+		return nil, s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		params := DidChangeTextDocumentParams{}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &responseError{Code: invalidParams, Message: err.Error()}
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		return nil, s.update(params.TextDocument.URI, text)
+	case "textDocument/didClose":
+		params := DidCloseTextDocumentParams{}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &responseError{Code: invalidParams, Message: err.Error()}
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, nil
+	case "textDocument/hover":
+		return s.positional(req, s.hover)
+	case "textDocument/definition":
+		return s.positional(req, s.definition)
+	case "textDocument/completion":
+		return s.positional(req, s.completion)
+	case "textDocument/documentSymbol":
+		return s.whole(req, s.symbols)
+	case "textDocument/formatting":
+		return s.whole(req, s.formatting)
+	}
+
+	if req.ID == nil {
+		return nil, nil
+	}
+	return nil, &responseError{Code: methodNotFound, Message: "method not supported: " + req.Method}
+}
+
+// positional decodes the parameters of a request about a position in a
+// document and passes them on to fn, converted to 1-based line and column.
+func (s *server) positional(req *request, fn func(uri string, d *document, line, column int) interface{}) (interface{}, *responseError) {
+	params := TextDocumentPositionParams{}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return nil, &responseError{Code: invalidParams, Message: err.Error()}
+	}
+
+	d, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return nil, nil
+	}
+
+	line := params.Position.Line + 1
+	return fn(params.TextDocument.URI, d, line, d.column(line, params.Position.Character)), nil
+}
+
+// whole decodes the parameters of a request about an entire document.
+func (s *server) whole(req *request, fn func(uri string, d *document) interface{}) (interface{}, *responseError) {
+	params := DocumentParams{}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return nil, &responseError{Code: invalidParams, Message: err.Error()}
+	}
+
+	d, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return nil, nil
+	}
+
+	return fn(params.TextDocument.URI, d), nil
+}
+
+func (s *server) update(uri, text string) *responseError {
+	d := newDocument(text)
+	s.docs[uri] = d
+
+	diagnostics := []Diagnostic{}
+	for _, issue := range lint.Source([]byte(text)) {
+		severity := severityWarning
+		if issue.Rule == lint.SYNTAX {
+			severity = severityError
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    d.wordRange(issue.Line, issue.Column),
+			Severity: severity,
+			Code:     issue.Rule,
+			Source:   "coff",
+			Message:  issue.Message,
+		})
+	}
+
+	err := s.write(notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  PublishDiagnosticsParams{URI: uri, Diagnostics: diagnostics},
+	})
+	if err != nil {
+		return &responseError{Code: internalError, Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (s *server) hover(uri string, d *document, line, column int) interface{} {
+	id := d.identAt(line, column)
+	if id == nil {
+		return nil
+	}
+
+	var code, doc string
+	if sym, ok := d.refs[id]; ok {
+		switch d.kind(sym) {
+		case "function":
+			code = "(function) " + signature(sym.name, sym.value.(*ast.FunctionLiteral))
+		case "parameter":
+			code = "(parameter) " + sym.name
+		case "module":
+			code = "(module) " + sym.name + " \"" + sym.module + "\""
+		case "class":
+			code = "(class) " + sym.name
+			if sym.class.Super != nil {
+				code += " < " + sym.class.Super.Value
+			}
+		case "struct":
+			code = "(struct) " + strings.TrimPrefix(sym.structure.String(), "export ")
+		default:
+			code = "(variable) " + sym.name
+			if kind := d.valueKind(sym.value); kind != "" {
+				code += ": " + kind
+			}
+		}
+	} else if std, ok := eval.LookupStd(id.Value); ok {
+		code = "(builtin) " + std.Signature
+		doc = std.Doc
+	} else {
+		return nil
+	}
+
+	value := "```coff\n" + code + "\n```"
+	if doc != "" {
+		value += "\n\n" + doc
+	}
+
+	return Hover{Contents: MarkupContent{Kind: "markdown", Value: value}, Range: d.identRange(id)}
+}
+
+func (s *server) definition(uri string, d *document, line, column int) interface{} {
+	id := d.identAt(line, column)
+	if id == nil {
+		return nil
+	}
+
+	sym, ok := d.refs[id]
+	if !ok {
+		return nil
+	}
+
+	return Location{URI: uri, Range: d.tokenRange(sym.tok.Line, sym.tok.Column, utf8.RuneCountInString(sym.name))}
+}
+
+func (s *server) completion(uri string, d *document, line, column int) interface{} {
+	items := []CompletionItem{}
+	seen := map[string]bool{}
+
+	for _, sym := range d.visible(line, column) {
+		seen[sym.name] = true
+		item := CompletionItem{Label: sym.name, Kind: completionVariable, Detail: d.kind(sym)}
+		if fn, ok := sym.value.(*ast.FunctionLiteral); ok {
+			item.Kind = completionFunction
+			item.Detail = signature(sym.name, fn)
+		}
+		if sym.structure != nil {
+			item.Kind = completionStruct
+		}
+		if sym.class != nil {
+			item.Kind = completionClass
+		}
+		items = append(items, item)
+	}
+
+	for _, name := range eval.Builtins() {
+		if seen[name] {
+			continue
+		}
+		std, _ := eval.LookupStd(name)
+		items = append(items, CompletionItem{Label: name, Kind: completionFunction, Detail: std.Signature})
+	}
+
+	return items
+}
+
+func (s *server) symbols(uri string, d *document) interface{} {
+	return d.documentSymbols(d.top)
+}
+
+func (d *document) documentSymbols(sc *scope) []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+	for _, sym := range sc.symbols {
+		if sym.param {
+			continue
+		}
+
+		ds := DocumentSymbol{
+			Name:           sym.name,
+			Kind:           symbolVariable,
+			Range:          d.tokenRange(sym.tok.Line, sym.tok.Column, utf8.RuneCountInString(sym.name)),
+			SelectionRange: d.tokenRange(sym.tok.Line, sym.tok.Column, utf8.RuneCountInString(sym.name)),
+		}
+		if sym.value != nil {
+			end := ast.End(sym.value)
+			ds.Range.End = d.position(end.Line, end.Column+utf8.RuneCountInString(end.Literal))
+		}
+		if st := sym.structure; st != nil {
+			ds.Kind = symbolStruct
+			ds.Range.End = d.position(st.EndToken.Line, st.EndToken.Column+1)
+			for _, f := range st.Fields {
+				r := d.tokenRange(f.Token.Line, f.Token.Column, utf8.RuneCountInString(f.Value))
+				ds.Children = append(ds.Children, DocumentSymbol{Name: f.Value, Kind: symbolField, Range: r, SelectionRange: r})
+			}
+		}
+		if cl := sym.class; cl != nil {
+			ds.Kind = symbolClass
+			ds.Range.End = d.position(cl.EndToken.Line, cl.EndToken.Column+1)
+			for _, m := range cl.Methods {
+				r := d.tokenRange(m.Token.Line, m.Token.Column, utf8.RuneCountInString(m.Name))
+				method := DocumentSymbol{Name: m.Name, Kind: symbolMethod, Detail: signature(m.Name, m), Range: r, SelectionRange: r}
+				method.Range.End = d.position(m.Body.EndToken.Line, m.Body.EndToken.Column+1)
+				if fs, ok := d.fnScopes[m]; ok {
+					method.Children = d.documentSymbols(fs)
+				}
+				ds.Children = append(ds.Children, method)
+			}
+		}
+		if fn, ok := sym.value.(*ast.FunctionLiteral); ok {
+			ds.Kind = symbolFunction
+			ds.Detail = signature("fun", fn)
+			if fs, ok := d.fnScopes[fn]; ok {
+				ds.Children = d.documentSymbols(fs)
+			}
+		}
+
+		symbols = append(symbols, ds)
+	}
+
+	return symbols
+}
+
+func (s *server) formatting(uri string, d *document) interface{} {
+	out, err := format.Source([]byte(d.text))
+	if err != nil {
+		return nil
+	}
+
+	last := len(d.lines)
+	end := d.position(last, utf8.RuneCountInString(d.lines[last-1])+1)
+
+	return []TextEdit{{Range: Range{End: end}, NewText: string(out)}}
+}
+
+// wordRange returns the range of the word starting at a 1-based
+// position, or an empty range there if no word starts there.
+func (d *document) wordRange(line, column int) Range {
+	length := 0
+	if line >= 1 && line <= len(d.lines) {
+		text := []rune(d.lines[line-1])
+		for i := column - 1; i >= 0 && i < len(text) && isWordChar(text[i]); i++ {
+			length++
+		}
+	}
+
+	return d.tokenRange(line, column, length)
+}
+
+func isWordChar(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_'
+}
+
+func (d *document) identRange(id *ast.Identifier) Range {
+	return d.tokenRange(id.Token.Line, id.Token.Column, utf8.RuneCountInString(id.Value))
+}
+
+// tokenRange converts a 1-based position and a length, both counted in
+// characters, to a range.
+func (d *document) tokenRange(line, column, length int) Range {
+	return Range{Start: d.position(line, column), End: d.position(line, column+length)}
+}
+
+// position converts a 1-based line and column, counted in characters as
+// the lexer does, to an LSP position, counted in UTF-16 code units.
+func (d *document) position(line, column int) Position {
+	if line < 1 {
+		return Position{}
+	}
+	pos := Position{Line: line - 1}
+	if line > len(d.lines) {
+		return pos
+	}
+	for i, r := range []rune(d.lines[line-1]) {
+		if i >= column-1 {
+			break
+		}
+		pos.Character += utf16.RuneLen(r)
+	}
+	return pos
+}
+
+// column converts the UTF-16 offset character in the 1-based line to a
+// 1-based column counted in characters.
+func (d *document) column(line, character int) int {
+	column := 1
+	if line < 1 || line > len(d.lines) {
+		return column + character
+	}
+	for _, r := range d.lines[line-1] {
+		if character <= 0 {
+			break
+		}
+		character -= utf16.RuneLen(r)
+		column++
+	}
+	return column
+}
@@ -0,0 +1,342 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const testURI = "file:///test.coff"
+
+const testSource = `def add = fun(a, b) {
+	def sum = a + b;
+	ret sum;
+};
+def five = 5;
+print(add(five, 1));
+def unused = 1;
+`
+
+// client drives a server the way an editor would.
+type client struct {
+	t             *testing.T
+	in            io.Writer
+	messages      chan map[string]interface{}
+	id            int
+	notifications []map[string]interface{}
+	done          chan error
+}
+
+func newClient(t *testing.T) *client {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	c := &client{t: t, in: inW, messages: make(chan map[string]interface{}, 100), done: make(chan error, 1)}
+	go func() {
+		c.done <- Serve(inR, outW)
+		outW.Close()
+	}()
+	go c.receive(bufio.NewReader(outR))
+
+	return c
+}
+
+func (c *client) send(msg map[string]interface{}) {
+	msg["jsonrpc"] = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+// receive reads messages from the server until it closes its output,
+// so that the server never blocks on a write.
+func (c *client) receive(out *bufio.Reader) {
+	defer close(c.messages)
+	for {
+		headers, err := textproto.NewReader(out).ReadMIMEHeader()
+		if err != nil {
+			return
+		}
+		length, _ := strconv.Atoi(headers.Get("Content-Length"))
+		body := make([]byte, length)
+		if _, err := io.ReadFull(out, body); err != nil {
+			return
+		}
+
+		msg := map[string]interface{}{}
+		if err := json.Unmarshal(body, &msg); err != nil {
+			return
+		}
+		c.messages <- msg
+	}
+}
+
+// call sends a request and returns its result, collecting any
+// notifications that arrive first.
+func (c *client) call(method string, params interface{}) interface{} {
+	c.id++
+	c.send(map[string]interface{}{"id": c.id, "method": method, "params": params})
+
+	for msg := range c.messages {
+		if _, ok := msg["id"]; !ok {
+			c.notifications = append(c.notifications, msg)
+			continue
+		}
+		if int(msg["id"].(float64)) != c.id {
+			c.t.Fatalf("response to wrong request: %v", msg)
+		}
+		if msg["error"] != nil {
+			c.t.Fatalf("%s failed: %v", method, msg["error"])
+		}
+		return msg["result"]
+	}
+
+	c.t.Fatalf("server closed the connection during %s", method)
+	return nil
+}
+
+func (c *client) notify(method string, params interface{}) {
+	c.send(map[string]interface{}{"method": method, "params": params})
+}
+
+func position(line, character int) map[string]interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": testURI},
+		"position":     map[string]interface{}{"line": line, "character": character},
+	}
+}
+
+func wholeDocument() map[string]interface{} {
+	return map[string]interface{}{"textDocument": map[string]interface{}{"uri": testURI}}
+}
+
+func openClient(t *testing.T) *client {
+	c := newClient(t)
+	c.call("initialize", map[string]interface{}{})
+	c.notify("initialized", map[string]interface{}{})
+	c.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": testURI, "languageId": "coff", "version": 1, "text": testSource},
+	})
+	return c
+}
+
+func (c *client) close() {
+	c.call("shutdown", nil)
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		c.t.Errorf("Serve returned error: %s", err)
+	}
+}
+
+func TestInitialize(t *testing.T) {
+	c := newClient(t)
+	result := c.call("initialize", map[string]interface{}{}).(map[string]interface{})
+	capabilities := result["capabilities"].(map[string]interface{})
+
+	for _, name := range []string{"hoverProvider", "definitionProvider", "completionProvider", "documentSymbolProvider", "documentFormattingProvider"} {
+		if capabilities[name] == nil {
+			t.Errorf("capability %s missing", name)
+		}
+	}
+	c.close()
+}
+
+func TestDiagnostics(t *testing.T) {
+	c := openClient(t)
+	c.call("textDocument/hover", position(0, 0)) // wait for didOpen to be handled
+
+	if len(c.notifications) != 1 {
+		t.Fatalf("expected 1 notification, got=%d", len(c.notifications))
+	}
+	params := c.notifications[0]["params"].(map[string]interface{})
+	diagnostics := params["diagnostics"].([]interface{})
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic, got=%v", diagnostics)
+	}
+
+	d := diagnostics[0].(map[string]interface{})
+	if d["code"] != "unused-def" || d["severity"].(float64) != 2 {
+		t.Errorf("wrong diagnostic: %v", d)
+	}
+	r := d["range"].(map[string]interface{})
+	start, end := r["start"].(map[string]interface{}), r["end"].(map[string]interface{})
+	if start["line"].(float64) != 6 || start["character"].(float64) != 4 || end["character"].(float64) != 10 {
+		t.Errorf("wrong range: %v", r)
+	}
+
+	c.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": testURI, "version": 2},
+		"contentChanges": []interface{}{map[string]interface{}{"text": "def = 1;"}},
+	})
+	c.call("textDocument/hover", position(0, 0))
+	params = c.notifications[1]["params"].(map[string]interface{})
+	d = params["diagnostics"].([]interface{})[0].(map[string]interface{})
+	if d["code"] != "syntax" || d["severity"].(float64) != 1 {
+		t.Errorf("wrong diagnostic: %v", d)
+	}
+	c.close()
+}
+
+func TestHover(t *testing.T) {
+	c := openClient(t)
+
+	tests := []struct {
+		line, character int
+		expected        string
+	}{
+		{5, 6, "(function) add(a, b)"},
+		{1, 15, "(parameter) b"},
+		{5, 11, "(variable) five: int"},
+		{5, 1, "(builtin) print(args...)"},
+	}
+
+	for _, tt := range tests {
+		result, ok := c.call("textDocument/hover", position(tt.line, tt.character)).(map[string]interface{})
+		if !ok {
+			t.Errorf("no hover at %d:%d", tt.line, tt.character)
+			continue
+		}
+		value := result["contents"].(map[string]interface{})["value"].(string)
+		if !strings.Contains(value, tt.expected) {
+			t.Errorf("hover at %d:%d wrong. expected to contain %q, got=%q", tt.line, tt.character, tt.expected, value)
+		}
+	}
+
+	if result := c.call("textDocument/hover", position(3, 0)); result != nil {
+		t.Errorf("expected no hover outside identifiers, got=%v", result)
+	}
+	c.close()
+}
+
+func TestDefinition(t *testing.T) {
+	c := openClient(t)
+
+	tests := []struct {
+		line, character       int
+		expectedLine, expChar int
+	}{
+		{5, 7, 0, 4},   // add
+		{5, 10, 4, 4},  // five
+		{1, 11, 0, 14}, // a
+		{2, 6, 1, 5},   // sum
+	}
+
+	for _, tt := range tests {
+		result, ok := c.call("textDocument/definition", position(tt.line, tt.character)).(map[string]interface{})
+		if !ok {
+			t.Errorf("no definition at %d:%d", tt.line, tt.character)
+			continue
+		}
+		start := result["range"].(map[string]interface{})["start"].(map[string]interface{})
+		if int(start["line"].(float64)) != tt.expectedLine || int(start["character"].(float64)) != tt.expChar {
+			t.Errorf("definition at %d:%d wrong. expected=%d:%d, got=%v", tt.line, tt.character, tt.expectedLine, tt.expChar, start)
+		}
+	}
+	c.close()
+}
+
+func TestCompletion(t *testing.T) {
+	c := openClient(t)
+
+	labels := func(line, character int) map[string]bool {
+		result := map[string]bool{}
+		for _, item := range c.call("textDocument/completion", position(line, character)).([]interface{}) {
+			result[item.(map[string]interface{})["label"].(string)] = true
+		}
+		return result
+	}
+
+	inside := labels(2, 5)
+	for _, name := range []string{"a", "b", "sum", "add", "five", "len", "print"} {
+		if !inside[name] {
+			t.Errorf("%s missing from completions inside add", name)
+		}
+	}
+
+	outside := labels(4, 0)
+	if outside["a"] || outside["sum"] || outside["five"] {
+		t.Errorf("completions outside add include names not yet in scope: %v", outside)
+	}
+	if !outside["add"] {
+		t.Errorf("add missing from completions after its definition")
+	}
+	c.close()
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	c := openClient(t)
+	symbols := c.call("textDocument/documentSymbol", wholeDocument()).([]interface{})
+
+	names := []string{}
+	for _, s := range symbols {
+		names = append(names, s.(map[string]interface{})["name"].(string))
+	}
+	if strings.Join(names, ",") != "add,five,unused" {
+		t.Errorf("wrong symbols: %v", names)
+	}
+
+	add := symbols[0].(map[string]interface{})
+	children := add["children"].([]interface{})
+	if add["kind"].(float64) != 12 || len(children) != 1 || children[0].(map[string]interface{})["name"] != "sum" {
+		t.Errorf("wrong symbol for add: %v", add)
+	}
+	c.close()
+}
+
+func TestFormatting(t *testing.T) {
+	c := openClient(t)
+	c.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": testURI, "version": 2},
+		"contentChanges": []interface{}{map[string]interface{}{"text": "def x=1\nprint(x)"}},
+	})
+
+	edits := c.call("textDocument/formatting", wholeDocument()).([]interface{})
+	if len(edits) != 1 {
+		t.Fatalf("expected 1 edit, got=%v", edits)
+	}
+	edit := edits[0].(map[string]interface{})
+	if edit["newText"] != "def x = 1;\nprint(x);\n" {
+		t.Errorf("wrong formatting: %q", edit["newText"])
+	}
+	end := edit["range"].(map[string]interface{})["end"].(map[string]interface{})
+	if end["line"].(float64) != 1 || end["character"].(float64) != 8 {
+		t.Errorf("edit does not cover the document: %v", edit["range"])
+	}
+	c.close()
+}
+
+func TestUTF16Positions(t *testing.T) {
+	c := openClient(t)
+	c.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": testURI, "version": 2},
+		"contentChanges": []interface{}{map[string]interface{}{"text": "def s = \"😀\"; def x = s; x;"}},
+	})
+
+	// The emoji is one code point but two UTF-16 code units.
+	result, ok := c.call("textDocument/definition", position(0, 25)).(map[string]interface{})
+	if !ok {
+		t.Fatalf("no definition at 0:25")
+	}
+	r := result["range"].(map[string]interface{})
+	start, end := r["start"].(map[string]interface{}), r["end"].(map[string]interface{})
+	if start["character"].(float64) != 18 || end["character"].(float64) != 19 {
+		t.Errorf("wrong definition range: %v", r)
+	}
+
+	edits := c.call("textDocument/formatting", wholeDocument()).([]interface{})
+	if len(edits) != 1 {
+		t.Fatalf("expected 1 edit, got=%v", edits)
+	}
+	end = edits[0].(map[string]interface{})["range"].(map[string]interface{})["end"].(map[string]interface{})
+	if end["line"].(float64) != 0 || end["character"].(float64) != 27 {
+		t.Errorf("edit does not cover the document: %v", end)
+	}
+	c.close()
+}
@@ -1,13 +1,43 @@
 package main
 
 import (
+	"coff-src/src/coff/eval"
+	"coff-src/src/coff/repl"
 	"fmt"
 	"os"
 	"os/user"
-	"coff-src/src/coff/repl"
+	"path/filepath"
 )
 
+// commands maps each subcommand to the function implementing it. A
+// command gets the arguments after its name and returns the exit code.
+var commands = map[string]func(args []string) int{
+	"dap":     runDap,
+	"debug":   runDebug,
+	"fmt":     runFmt,
+	"lint":    runLint,
+	"lsp":     runLsp,
+	"profile": runProfile,
+	"run":     runRun,
+	"test":    runTest,
+}
+
 func main() {
+	// Imports not found next to the importing file are looked up in
+	// the directories listed in COFFPATH.
+	if path := os.Getenv("COFFPATH"); path != "" {
+		eval.SearchPath = filepath.SplitList(path)
+	}
+
+	if len(os.Args) > 1 {
+		command, ok := commands[os.Args[1]]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "coff: unknown command %q\n", os.Args[1])
+			os.Exit(2)
+		}
+		os.Exit(command(os.Args[2:]))
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -16,5 +46,3 @@ func main() {
 	fmt.Printf("I'm ready to take your commands!\n")
 	repl.Start(os.Stdin, os.Stdout)
 }
-
-
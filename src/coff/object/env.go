@@ -1,5 +1,7 @@
 package object
 
+import "sort"
+
 func NewEnclosedEnv(outer *Env) *Env {
 	env := NewEnv()
 	env.outer = outer
@@ -14,6 +16,7 @@ func NewEnv() *Env {
 type Env struct {
 	store map[string]Object
 	outer *Env
+	path string
 }
 
 func (e *Env) Get(name string) (Object, bool) {
@@ -27,4 +30,38 @@ func (e *Env) Get(name string) (Object, bool) {
 func (e *Env) Set(name string, val Object) Object {
 	e.store[name] = val
 	return val
-}
\ No newline at end of file
+}
+
+// SetPath records that e is the outermost environment of the source file
+// at path, which imports are resolved against.
+func (e *Env) SetPath(path string) {
+	e.path = path
+}
+
+// Path returns the path of the source file e belongs to, or "" if it is
+// not known.
+func (e *Env) Path() string {
+	for ; e != nil; e = e.outer {
+		if e.path != "" {
+			return e.path
+		}
+	}
+	return ""
+}
+
+// Outer returns the enclosing environment, or nil for the outermost one.
+func (e *Env) Outer() *Env {
+	return e.outer
+}
+
+// Names returns the names bound directly in e, not in its enclosing
+// environments, in sorted order.
+func (e *Env) Names() []string {
+	names := []string{}
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,99 @@
+package object
+
+// Equal reports whether a and b are equal values. Strings, arrays,
+// tuples, hashes, sets and structs are compared by their contents, which
+// may refer back to themselves; functions, classes, instances and other
+// values are equal only to themselves.
+func Equal(a, b Object) bool {
+	return equal(a, b, map[[2]Object]bool{})
+}
+
+// equal compares a and b. Pairs already being compared further up are
+// taken to be equal, so that comparing cyclic values terminates.
+func equal(a, b Object, comparing map[[2]Object]bool) bool {
+	if a == b {
+		return true
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *Int:
+		return a.Value == b.(*Int).Value
+	case *Bool:
+		return a.Value == b.(*Bool).Value
+	case *Str:
+		return a.Value == b.(*Str).Value
+	case *Null:
+		return true
+	}
+
+	pair := [2]Object{a, b}
+	if comparing[pair] {
+		return true
+	}
+	comparing[pair] = true
+	defer delete(comparing, pair)
+
+	switch a := a.(type) {
+	case *Arr:
+		b := b.(*Arr)
+		if len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		for i := range a.Elements {
+			if !equal(a.Elements[i], b.Elements[i], comparing) {
+				return false
+			}
+		}
+		return true
+	case *Hash:
+		b := b.(*Hash)
+		if a.Len() != b.Len() {
+			return false
+		}
+		for _, pair := range a.Pairs() {
+			other, ok := b.Get(pair.Key)
+			if !ok || !equal(pair.Value, other, comparing) {
+				return false
+			}
+		}
+		return true
+	case *Set:
+		b := b.(*Set)
+		if a.Len() != b.Len() {
+			return false
+		}
+		for _, el := range a.Elements() {
+			if !b.Has(el) {
+				return false
+			}
+		}
+		return true
+	case *Tuple:
+		b := b.(*Tuple)
+		if len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		for i := range a.Elements {
+			if !equal(a.Elements[i], b.Elements[i], comparing) {
+				return false
+			}
+		}
+		return true
+	case *Struct:
+		b := b.(*Struct)
+		if a.Def != b.Def {
+			return false
+		}
+		for i := range a.Values {
+			if !equal(a.Values[i], b.Values[i], comparing) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,106 @@
+package object
+
+// Hash maps keys to values, remembering the order in which keys were
+// first set. Keys are found through their hash keys and then told apart
+// by equality, so keys whose hash keys collide do not overwrite each
+// other. The zero Hash is empty and ready to use.
+type Hash struct {
+	pairs []HashPair
+	index map[HashKey][]int // the positions in pairs of the keys with each hash key
+}
+
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+// NewHash returns an empty hash.
+func NewHash() *Hash {
+	return &Hash{index: map[HashKey][]int{}}
+}
+
+// find returns the position of key in h.pairs, or -1 if h does not hold
+// it. ok is false if key cannot be used as a hash key.
+func (h *Hash) find(key Object) (hashed HashKey, pos int, ok bool) {
+	hashed, ok = HashKeyOf(key)
+	if !ok {
+		return hashed, -1, false
+	}
+	for _, i := range h.index[hashed] {
+		if Equal(h.pairs[i].Key, key) {
+			return hashed, i, true
+		}
+	}
+	return hashed, -1, true
+}
+
+// Get returns the value stored under key.
+func (h *Hash) Get(key Object) (Object, bool) {
+	_, pos, _ := h.find(key)
+	if pos < 0 {
+		return nil, false
+	}
+	return h.pairs[pos].Value, true
+}
+
+// Set stores value under key, or returns false if key cannot be used as
+// a hash key. A key already present keeps its place in the order.
+func (h *Hash) Set(key, value Object) bool {
+	hashed, pos, ok := h.find(key)
+	if !ok {
+		return false
+	}
+	if pos >= 0 {
+		h.pairs[pos].Value = value
+		return true
+	}
+
+	if h.index == nil {
+		h.index = map[HashKey][]int{}
+	}
+	h.index[hashed] = append(h.index[hashed], len(h.pairs))
+	h.pairs = append(h.pairs, HashPair{Key: key, Value: value})
+	return true
+}
+
+// Delete removes key and reports whether h held it.
+func (h *Hash) Delete(key Object) bool {
+	hashed, pos, _ := h.find(key)
+	if pos < 0 {
+		return false
+	}
+
+	bucket := []int{}
+	for _, i := range h.index[hashed] {
+		if i != pos {
+			bucket = append(bucket, i)
+		}
+	}
+	if len(bucket) == 0 {
+		delete(h.index, hashed)
+	} else {
+		h.index[hashed] = bucket
+	}
+
+	// The pairs after the removed one move down by one.
+	h.pairs = append(h.pairs[:pos], h.pairs[pos+1:]...)
+	for _, positions := range h.index {
+		for j, i := range positions {
+			if i > pos {
+				positions[j] = i - 1
+			}
+		}
+	}
+	return true
+}
+
+// Len returns the number of keys in h.
+func (h *Hash) Len() int {
+	return len(h.pairs)
+}
+
+// Pairs returns the pairs of h in insertion order. The slice must not be
+// modified.
+func (h *Hash) Pairs() []HashPair {
+	return h.pairs
+}
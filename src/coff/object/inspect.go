@@ -0,0 +1,26 @@
+package object
+
+// inspector is a value that can hold other values, and so may hold
+// itself.
+type inspector interface {
+	Object
+	inspect(seen map[Object]bool) string
+	placeholder() string
+}
+
+// inspect returns the Inspect of obj. Values already being inspected
+// further up print as a placeholder such as {...}, so that printing a
+// cyclic value terminates.
+func inspect(obj Object, seen map[Object]bool) string {
+	in, ok := obj.(inspector)
+	if !ok {
+		return obj.Inspect()
+	}
+	if seen[obj] {
+		return in.placeholder()
+	}
+
+	seen[obj] = true
+	defer delete(seen, obj)
+	return in.inspect(seen)
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"bytes"
 	"hash/fnv"
+	"encoding/binary"
 )
 
 type ObjectType string
@@ -21,21 +22,36 @@ const (
 	STD_OBJ = "STD"
 	ARR_OBJ = "ARR"
 	HASH_OBJ = "HASH"
+	MODULE_OBJ = "MODULE"
+	STRUCT_TYPE_OBJ = "STRUCT_TYPE"
+	STRUCT_OBJ = "STRUCT"
+	CLASS_OBJ = "CLASS"
+	INSTANCE_OBJ = "INSTANCE"
+	BOUND_METHOD_OBJ = "BOUND_METHOD"
+	SUPER_OBJ = "SUPER"
+	ERROR_VALUE_OBJ = "ERROR"
+	TUPLE_OBJ = "TUPLE"
+	SET_OBJ = "SET"
+)
+
+// The kinds of errors. Builtin operations fail with the kind describing
+// what went wrong; a thrown value that is not an error has kind ERROR.
+const (
+	ERROR = "Error"
+	TYPE_ERROR = "TypeError"
+	ARGUMENT_ERROR = "ArgumentError"
+	NAME_ERROR = "NameError"
+	MEMBER_ERROR = "MemberError"
+	MATCH_ERROR = "MatchError"
+	ARITHMETIC_ERROR = "ArithmeticError"
+	IMPORT_ERROR = "ImportError"
+	ASSERTION_ERROR = "AssertionError"
 )
 
 type Hashable interface {
 	HashKey() HashKey
 }
 
-type Hash struct {
-	Pairs map[HashKey]HashPair
-}
-
-type HashPair struct {
-	Key Object
-	Value Object
-}
-
 type HashKey struct {
 	Type ObjectType
 	Value uint64
@@ -45,19 +61,208 @@ type Arr struct {
 	Elements []Object
 }
 
+// Tuple is an immutable sequence. Unlike arrays, tuples of hashable
+// values can be used as hash keys.
+type Tuple struct {
+	Elements []Object
+}
+
 type StdFunction func(args ...Object) Object
 type Std struct {
 	Fun StdFunction
+	Signature string // how the function is called, e.g. "len(x)"
+	Doc string // a one-sentence description
 }
 
+// Module is an imported source file. Its exported definitions are read
+// from its environment, so they reflect later changes to them.
+type Module struct {
+	Path string // the absolute path of the file
+	Env *Env
+	Exports map[string]bool
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string { return "<module " + m.Path + ">" }
+
+// Get returns the exported definition called name.
+func (m *Module) Get(name string) (Object, bool) {
+	if !m.Exports[name] {
+		return nil, false
+	}
+	return m.Env.Get(name)
+}
+
+// StructType is a declared struct. Calling it with a value for each field,
+// in order, creates a Struct.
+type StructType struct {
+	Name string
+	Fields []string
+}
+
+func (st *StructType) Type() ObjectType { return STRUCT_TYPE_OBJ }
+func (st *StructType) Inspect() string { return "<struct " + st.Name + ">" }
+
+// Field returns the position of the field called name.
+func (st *StructType) Field(name string) (int, bool) {
+	for i, f := range st.Fields {
+		if f == name {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// Struct is a value of a StructType. It holds exactly the declared fields.
+type Struct struct {
+	Def *StructType
+	Values []Object // in the order of Def.Fields
+}
+
+func (s *Struct) Type() ObjectType { return STRUCT_OBJ }
+func (s *Struct) Inspect() string { return inspect(s, map[Object]bool{}) }
+func (s *Struct) placeholder() string { return s.Def.Name + "{...}" }
+func (s *Struct) inspect(seen map[Object]bool) string {
+	fields := []string{}
+	for i, name := range s.Def.Fields {
+		fields = append(fields, name + ": " + inspect(s.Values[i], seen))
+	}
+
+	return s.Def.Name + "{" + strings.Join(fields, ", ") + "}"
+}
+
+// Get returns the value of the field called name.
+func (s *Struct) Get(name string) (Object, bool) {
+	i, ok := s.Def.Field(name)
+	if !ok {
+		return nil, false
+	}
+	return s.Values[i], true
+}
+
+// Set changes the value of the field called name. It reports false if
+// there is no such field.
+func (s *Struct) Set(name string, value Object) bool {
+	i, ok := s.Def.Field(name)
+	if !ok {
+		return false
+	}
+	s.Values[i] = value
+	return true
+}
+
+// Class is a declared class. Calling it creates an Instance and passes
+// the arguments to its init method, if it has one.
+type Class struct {
+	Name string
+	Super *Class // the class it extends, or nil
+	Methods map[string]*Function
+}
+
+func (c *Class) Type() ObjectType { return CLASS_OBJ }
+func (c *Class) Inspect() string { return "<class " + c.Name + ">" }
+
+// Method returns the method called name, looking through the classes c
+// extends, and the class that defines it.
+func (c *Class) Method(name string) (*Function, *Class, bool) {
+	for ; c != nil; c = c.Super {
+		if fn, ok := c.Methods[name]; ok {
+			return fn, c, true
+		}
+	}
+	return nil, nil, false
+}
+
+// Extends reports whether c is other or a class derived from it.
+func (c *Class) Extends(other *Class) bool {
+	for ; c != nil; c = c.Super {
+		if c == other {
+			return true
+		}
+	}
+	return false
+}
+
+// Instance is an object created by calling a Class. Its fields are set
+// by assigning to them, usually in the init method.
+type Instance struct {
+	Class *Class
+	Fields map[string]Object
+	order []string // field names in the order they were first set
+}
+
+func NewInstance(class *Class) *Instance {
+	return &Instance{Class: class, Fields: map[string]Object{}}
+}
+
+func (i *Instance) Type() ObjectType { return INSTANCE_OBJ }
+func (i *Instance) Inspect() string { return inspect(i, map[Object]bool{}) }
+func (i *Instance) placeholder() string { return i.Class.Name + "{...}" }
+func (i *Instance) inspect(seen map[Object]bool) string {
+	fields := []string{}
+	for _, name := range i.order {
+		fields = append(fields, name + ": " + inspect(i.Fields[name], seen))
+	}
+
+	return i.Class.Name + "{" + strings.Join(fields, ", ") + "}"
+}
+
+// Set sets the field called name.
+func (i *Instance) Set(name string, value Object) {
+	if _, ok := i.Fields[name]; !ok {
+		i.order = append(i.order, name)
+	}
+	i.Fields[name] = value
+}
+
+// Names returns the names of the fields in the order they were first set.
+func (i *Instance) Names() []string {
+	return i.order
+}
+
+// BoundMethod is a method together with the instance it was looked up
+// on, which the method receives as self.
+type BoundMethod struct {
+	Receiver *Instance
+	Fun *Function
+	Class *Class // the class that defines Fun; super refers to its superclass
+}
+
+func (bm *BoundMethod) Type() ObjectType { return BOUND_METHOD_OBJ }
+func (bm *BoundMethod) Inspect() string { return bm.Fun.Inspect() }
+
+// Super is the value of super in a method: the receiver seen as an
+// instance of the superclass of the class defining the method.
+type Super struct {
+	Receiver *Instance
+	Class *Class
+}
+
+func (s *Super) Type() ObjectType { return SUPER_OBJ }
+func (s *Super) Inspect() string { return "<super " + s.Class.Name + ">" }
+
 type Function struct {
-	Parameters []*ast.Identifier
+	Name string // the name it was defined with, if any
+	Parameters []ast.Pattern
 	Body *ast.BlockStatement
 	Env *Env
 }
 
+// Error is a failure unwinding the program until a try catches it.
 type Error struct {
 	Message string
+	Kind string
+	Value Object // the thrown value, if it was not an error
+	Path string // the file the error was raised in, if known
+	Line int // the position it was raised at; 0 until known
+	Column int
+	Stack []string // the calls in progress, innermost first
+}
+
+// ErrorValue is a caught error as seen by the catch block. Unlike Error
+// it is an ordinary value and does not unwind the program.
+type ErrorValue struct {
+	Err *Error
 }
 
 type RetVal struct {
@@ -100,6 +305,9 @@ func (rv *RetVal) Inspect() string { return rv.Value.Inspect() }
 func (e *Error) Type() ObjectType { return ERR_OBJ }
 func (e *Error) Inspect() string { return "ERROR: " + e.Message }
 
+func (ev *ErrorValue) Type() ObjectType { return ERROR_VALUE_OBJ }
+func (ev *ErrorValue) Inspect() string { return ev.Err.Kind + ": " + ev.Err.Message }
+
 func (f *Function) Type() ObjectType { return FUN_OBJ }
 func (f *Function) Inspect() string {
 	var out bytes.Buffer
@@ -126,12 +334,14 @@ func (std *Std) Type() ObjectType { return STD_OBJ }
 func (std *Std) Inspect() string { return "std function" }
 
 func (a *Arr) Type() ObjectType { return ARR_OBJ }
-func (a *Arr) Inspect() string {
+func (a *Arr) Inspect() string { return inspect(a, map[Object]bool{}) }
+func (a *Arr) placeholder() string { return "[...]" }
+func (a *Arr) inspect(seen map[Object]bool) string {
 	var out bytes.Buffer
 	elements := []string{}
 
 	for _, e := range a.Elements {
-		elements = append(elements, e.Inspect())
+		elements = append(elements, inspect(e, seen))
 	}
 
 	out.WriteString("[")
@@ -162,13 +372,57 @@ func (s *Str) HashKey() HashKey {
 	return HashKey{Type: s.Type(), Value: h.Sum64()}
 }
 
+func (t *Tuple) HashKey() HashKey {
+	h := fnv.New64a()
+	for _, el := range t.Elements {
+		key := el.(Hashable).HashKey()
+		h.Write([]byte(key.Type))
+		binary.Write(h, binary.LittleEndian, key.Value)
+	}
+	return HashKey{Type: t.Type(), Value: h.Sum64()}
+}
+
+// HashKeyOf returns the hash key of obj, or false if obj cannot be used
+// as a hash key. Tuples can only if all their elements can.
+func HashKeyOf(obj Object) (HashKey, bool) {
+	if t, ok := obj.(*Tuple); ok {
+		for _, el := range t.Elements {
+			if _, ok := HashKeyOf(el); !ok {
+				return HashKey{}, false
+			}
+		}
+	}
+	hashable, ok := obj.(Hashable)
+	if !ok {
+		return HashKey{}, false
+	}
+	return hashable.HashKey(), true
+}
+
+func (t *Tuple) Type() ObjectType { return TUPLE_OBJ }
+func (t *Tuple) Inspect() string { return inspect(t, map[Object]bool{}) }
+func (t *Tuple) placeholder() string { return "(...)" }
+func (t *Tuple) inspect(seen map[Object]bool) string {
+	elements := []string{}
+	for _, el := range t.Elements {
+		elements = append(elements, inspect(el, seen))
+	}
+	if len(elements) == 1 {
+		return "(" + elements[0] + ",)"
+	}
+
+	return "(" + strings.Join(elements, ", ") + ")"
+}
+
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
-func (h *Hash) Inspect() string {
+func (h *Hash) Inspect() string { return inspect(h, map[Object]bool{}) }
+func (h *Hash) placeholder() string { return "{...}" }
+func (h *Hash) inspect(seen map[Object]bool) string {
 	var out bytes.Buffer
 	
 	pairs := []string{}
-	for _, pair := range h.Pairs {
-		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
+	for _, pair := range h.Pairs() {
+		pairs = append(pairs, fmt.Sprintf("%s: %s", inspect(pair.Key, seen), inspect(pair.Value, seen)))
 	}
 
 	out.WriteString("{")
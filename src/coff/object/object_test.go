@@ -20,4 +20,161 @@ func TestStrHashKey(t *testing.T) {
 	if hello1.HashKey() == diff1.HashKey() {
 		t.Errorf("strings with different content have same hash keys")
 	}
-}
\ No newline at end of file
+}
+func TestEqual(t *testing.T) {
+	fn := &Function{}
+	cyclic := func(n int64) *Arr {
+		arr := &Arr{Elements: []Object{&Int{Value: n}}}
+		arr.Elements = append(arr.Elements, arr)
+		return arr
+	}
+
+	tests := []struct {
+		a, b     Object
+		expected bool
+	}{
+		{&Int{Value: 1}, &Int{Value: 1}, true},
+		{&Int{Value: 1}, &Str{Value: "1"}, false},
+		{&Str{Value: "a"}, &Str{Value: "a"}, true},
+		{&Arr{Elements: []Object{&Str{Value: "a"}}}, &Arr{Elements: []Object{&Str{Value: "a"}}}, true},
+		{&Arr{Elements: []Object{&Int{Value: 1}}}, &Arr{}, false},
+		{fn, fn, true},
+		{fn, &Function{}, false},
+		{cyclic(1), cyclic(1), true},
+		{cyclic(1), cyclic(2), false},
+	}
+
+	for i, tt := range tests {
+		if got := Equal(tt.a, tt.b); got != tt.expected {
+			t.Errorf("tests[%d]: Equal(%s, %s) wrong. expected=%t, got=%t", i, tt.a.Type(), tt.b.Type(), tt.expected, got)
+		}
+	}
+}
+
+func TestTupleHashKey(t *testing.T) {
+	pair := func(a Object, b Object) *Tuple { return &Tuple{Elements: []Object{a, b}} }
+
+	one := pair(&Int{Value: 1}, &Str{Value: "a"})
+	same := pair(&Int{Value: 1}, &Str{Value: "a"})
+	swapped := pair(&Str{Value: "a"}, &Int{Value: 1})
+
+	if one.HashKey() != same.HashKey() {
+		t.Errorf("tuples with same elements have different hash keys")
+	}
+	if one.HashKey() == swapped.HashKey() {
+		t.Errorf("tuples with different elements have same hash keys")
+	}
+	if _, ok := HashKeyOf(pair(&Int{Value: 1}, &Arr{})); ok {
+		t.Errorf("tuple holding an array has a hash key")
+	}
+}
+
+// collidingKey is a key whose hash key is the same for all its values.
+type collidingKey struct{ name string }
+
+func (k *collidingKey) Type() ObjectType { return "COLLIDING" }
+func (k *collidingKey) Inspect() string  { return k.name }
+func (k *collidingKey) HashKey() HashKey { return HashKey{Type: k.Type(), Value: 1} }
+
+func TestHashCollisions(t *testing.T) {
+	a, b := &collidingKey{"a"}, &collidingKey{"b"}
+
+	h := NewHash()
+	h.Set(a, &Int{Value: 1})
+	if _, ok := h.Get(b); ok {
+		t.Errorf("Get found a value for a key that was not set")
+	}
+	h.Set(b, &Int{Value: 2})
+	if h.Len() != 2 {
+		t.Fatalf("colliding key overwrote the other. got %d pairs", h.Len())
+	}
+	if v, ok := h.Get(a); !ok || v.(*Int).Value != 1 {
+		t.Errorf("wrong value for a. got=%v", v)
+	}
+	if v, ok := h.Get(b); !ok || v.(*Int).Value != 2 {
+		t.Errorf("wrong value for b. got=%v", v)
+	}
+
+	if !h.Delete(a) || h.Len() != 1 {
+		t.Fatalf("Delete did not remove a")
+	}
+	if v, ok := h.Get(b); !ok || v.(*Int).Value != 2 {
+		t.Errorf("wrong value for b after deleting a. got=%v", v)
+	}
+}
+
+func TestInspectCycles(t *testing.T) {
+	arr := &Arr{}
+	arr.Elements = []Object{&Int{Value: 1}, arr}
+	if arr.Inspect() != "[1, [...]]" {
+		t.Errorf("wrong inspect of a cyclic array. got=%q", arr.Inspect())
+	}
+
+	point := &StructType{Name: "Point", Fields: []string{"x", "next"}}
+	s := &Struct{Def: point, Values: []Object{&Int{Value: 1}, nil}}
+	s.Values[1] = s
+	if s.Inspect() != "Point{x: 1, next: Point{...}}" {
+		t.Errorf("wrong inspect of a cyclic struct. got=%q", s.Inspect())
+	}
+
+	h := NewHash()
+	h.Set(&Str{Value: "t"}, &Tuple{Elements: []Object{arr, h}})
+	if h.Inspect() != "{t: ([1, [...]], {...})}" {
+		t.Errorf("wrong inspect of a cyclic hash. got=%q", h.Inspect())
+	}
+}
+
+func TestSet(t *testing.T) {
+	s := NewSet()
+	for _, v := range []Object{&Int{Value: 2}, &Str{Value: "a"}, &Int{Value: 2}, &Tuple{Elements: []Object{&Int{Value: 1}}}} {
+		if !s.Add(v) {
+			t.Errorf("Add(%s) failed", v.Inspect())
+		}
+	}
+	if s.Add(&Arr{}) {
+		t.Errorf("Add accepted an array")
+	}
+	if s.Len() != 3 {
+		t.Errorf("wrong length. expected=3, got=%d", s.Len())
+	}
+	if s.Inspect() != "#{2, a, (1,)}" {
+		t.Errorf("wrong inspect. got=%q", s.Inspect())
+	}
+	if !s.Has(&Str{Value: "a"}) || s.Has(&Str{Value: "b"}) || s.Has(&Arr{}) {
+		t.Errorf("wrong membership")
+	}
+	if !s.Remove(&Int{Value: 2}) || s.Remove(&Int{Value: 2}) {
+		t.Errorf("Remove reported wrongly")
+	}
+
+	other := NewSet()
+	other.Add(&Tuple{Elements: []Object{&Int{Value: 1}}})
+	other.Add(&Str{Value: "a"})
+	if !Equal(s, other) {
+		t.Errorf("sets with the same elements in another order are not equal")
+	}
+}
+
+func TestHashOrder(t *testing.T) {
+	h := &Hash{}
+	for _, key := range []string{"c", "a", "d", "b"} {
+		h.Set(&Str{Value: key}, &Int{Value: int64(len(key))})
+	}
+	h.Set(&Str{Value: "a"}, &Int{Value: 9})
+	h.Delete(&Str{Value: "d"})
+	h.Set(&Str{Value: "d"}, &Null{})
+
+	keys := ""
+	for _, pair := range h.Pairs() {
+		keys += pair.Key.Inspect()
+	}
+	if keys != "cabd" {
+		t.Errorf("wrong order. expected=%q, got=%q", "cabd", keys)
+	}
+	if h.Inspect() != "{c: 1, a: 9, b: 1, d: null}" {
+		t.Errorf("wrong inspect. got=%q", h.Inspect())
+	}
+	if h.Delete(&Str{Value: "x"}) {
+		t.Errorf("Delete removed a key that was not set")
+	}
+}
@@ -0,0 +1,59 @@
+package object
+
+import "strings"
+
+// Set is a collection of distinct hashable values. It is iterated in the
+// order values were first added, which also makes Inspect deterministic.
+type Set struct {
+	members Hash
+}
+
+// NewSet returns an empty set.
+func NewSet() *Set {
+	return &Set{}
+}
+
+// Add adds value to s, or returns false if value is not hashable.
+func (s *Set) Add(value Object) bool {
+	if s.Has(value) {
+		return true
+	}
+	return s.members.Set(value, value)
+}
+
+// Remove removes value from s and reports whether s held it.
+func (s *Set) Remove(value Object) bool {
+	return s.members.Delete(value)
+}
+
+// Has reports whether s holds value.
+func (s *Set) Has(value Object) bool {
+	_, ok := s.members.Get(value)
+	return ok
+}
+
+// Len returns the number of values in s.
+func (s *Set) Len() int {
+	return s.members.Len()
+}
+
+// Elements returns the values of s in the order they were added.
+func (s *Set) Elements() []Object {
+	elements := []Object{}
+	for _, pair := range s.members.Pairs() {
+		elements = append(elements, pair.Key)
+	}
+	return elements
+}
+
+func (s *Set) Type() ObjectType    { return SET_OBJ }
+func (s *Set) Inspect() string     { return inspect(s, map[Object]bool{}) }
+func (s *Set) placeholder() string { return "#{...}" }
+func (s *Set) inspect(seen map[Object]bool) string {
+	elements := []string{}
+	for _, el := range s.Elements() {
+		elements = append(elements, inspect(el, seen))
+	}
+
+	return "#{" + strings.Join(elements, ", ") + "}"
+}
@@ -11,26 +11,53 @@ import (
 const (
 	_ int = iota
 	LOWEST // 1
+	OR
+	AND
+	NOT
 	EQUALS
+	IN
 	LESSGREATER
+	BIT_OR
+	BIT_XOR
+	BIT_AND
+	SHIFT
 	SUM
 	PRODUCT
 	PREFIX
+	POWER
 	CALL
-	INDEX // 8
+	INDEX // 17
+	MEMBER
 )
 
 var precedences = map[token.TokenType]int {
+	token.OR:		OR,
+	token.AND:		AND,
 	token.EQ: 		EQUALS,
 	token.NOT_EQ:	EQUALS,
 	token.LT:		LESSGREATER,
 	token.GT:		LESSGREATER,
+	token.LT_EQ:	LESSGREATER,
+	token.GT_EQ:	LESSGREATER,
+	token.IS:		LESSGREATER,
+	token.IN:		IN,
+	token.NOT:		IN,
 	token.PLUS:		SUM,
 	token.MINUS:	SUM,
 	token.DIV:		PRODUCT,
 	token.MULT:		PRODUCT,
+	token.MOD:		PRODUCT,
+	token.FLOOR_DIV:	PRODUCT,
+	token.FLOOR_MOD:	PRODUCT,
+	token.POW:		POWER,
+	token.BIT_OR:	BIT_OR,
+	token.BIT_XOR:	BIT_XOR,
+	token.BIT_AND:	BIT_AND,
+	token.SHL:		SHIFT,
+	token.SHR:		SHIFT,
 	token.LPAR: 	CALL,
 	token.LBRACK: 	INDEX,
+	token.DOT:		MEMBER,
 }
 
 type (
@@ -38,10 +65,17 @@ type (
 	infixParseFn func(ast.Expression) ast.Expression
 )
 
+// ParseError is a syntax error together with the token it was found at.
+type ParseError struct {
+	Token token.Token
+	Message string
+}
+
 type Parser struct {
 	l *lexer.Lexer
 
 	errors []string
+	parseErrors []ParseError
 
 	currToken token.Token
 	peekToken token.Token
@@ -61,6 +95,8 @@ func New(l *lexer.Lexer) *Parser  {
 	p.registerPrefix(token.INT, p.parseIntLiteral)
 	p.registerPrefix(token.FAC, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.NOT, p.parsePrefixExpression)
+	p.registerPrefix(token.BIT_NOT, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.LPAR, p.parseGroupedExpression)
@@ -69,6 +105,9 @@ func New(l *lexer.Lexer) *Parser  {
 	p.registerPrefix(token.STR, p.parseStrLiteral)
 	p.registerPrefix(token.LBRACK, p.parseArrLiteral)
 	p.registerPrefix(token.LBRA, p.parseHashLiteral)
+	p.registerPrefix(token.HASH, p.parseSetLiteral)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
+	p.registerPrefix(token.TRY, p.parseTryExpression)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
@@ -78,9 +117,26 @@ func New(l *lexer.Lexer) *Parser  {
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
+	p.registerInfix(token.IS, p.parseInfixExpression)
+	p.registerInfix(token.IN, p.parseInfixExpression)
+	p.registerInfix(token.NOT, p.parseNotInExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.MOD, p.parseInfixExpression)
+	p.registerInfix(token.FLOOR_DIV, p.parseInfixExpression)
+	p.registerInfix(token.FLOOR_MOD, p.parseInfixExpression)
+	p.registerInfix(token.POW, p.parseInfixExpression)
+	p.registerInfix(token.BIT_AND, p.parseInfixExpression)
+	p.registerInfix(token.BIT_OR, p.parseInfixExpression)
+	p.registerInfix(token.BIT_XOR, p.parseInfixExpression)
+	p.registerInfix(token.SHL, p.parseInfixExpression)
+	p.registerInfix(token.SHR, p.parseInfixExpression)
 	p.registerInfix(token.LPAR, p.parseCallExpression)
 	p.registerInfix(token.LBRACK, p.parseIdxExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
 
 	p.nextToken() // to set currToken & peekToken
 	p.nextToken()
@@ -102,6 +158,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 
 		value := p.parseExpression(LOWEST)
 		hash.Pairs[key] = value
+		hash.Keys = append(hash.Keys, key)
 		if !p.peekTokenIs(token.RBRA) && !p.expectPeek(token.COMMA) {
 			return nil
 		}
@@ -110,15 +167,22 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	if !p.expectPeek(token.RBRA) {
 		return nil
 	}
+	hash.EndToken = p.currToken
 	
 	return hash
 }
 
 func (p *Parser) parseIdxExpression(left ast.Expression) ast.Expression {
 	exp := &ast.IdxExpression{Token: p.currToken, Left: left}
+	if p.peekTokenIs(token.COLON) {
+		return p.parseSliceExpression(exp.Token, left, nil)
+	}
 	p.nextToken()
 
 	exp.Index = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.COLON) {
+		return p.parseSliceExpression(exp.Token, left, exp.Index)
+	}
 	if !p.expectPeek(token.RBRACK) {
 		return nil
 	}
@@ -126,12 +190,58 @@ func (p *Parser) parseIdxExpression(left ast.Expression) ast.Expression {
 	return exp
 }
 
+// parseSliceExpression parses the rest of a[low:high:step] from the
+// first ':' on; low has been parsed already.
+func (p *Parser) parseSliceExpression(tok token.Token, left, low ast.Expression) ast.Expression {
+	exp := &ast.SliceExpression{Token: tok, Left: left, Low: low}
+	p.nextToken() // the ':'
+
+	if !p.peekTokenIs(token.COLON) && !p.peekTokenIs(token.RBRACK) {
+		p.nextToken()
+		exp.High = p.parseExpression(LOWEST)
+	}
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		if !p.peekTokenIs(token.RBRACK) {
+			p.nextToken()
+			exp.Step = p.parseExpression(LOWEST)
+		}
+	}
+	if !p.expectPeek(token.RBRACK) {
+		return nil
+	}
+	exp.EndToken = p.currToken
+
+	return exp
+}
+
+func (p *Parser) parseMemberExpression(object ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.currToken, Object: object}
+	if !p.expectPeek(token.ID) {
+		return nil
+	}
+	exp.Member = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+	return exp
+}
+
 func (p *Parser) parseArrLiteral() ast.Expression {
 	array := &ast.ArrLiteral{Token: p.currToken}
 	array.Elements = p.parseExpressionList(token.RBRACK)
+	array.EndToken = p.currToken
 	return array
 }
 
+func (p *Parser) parseSetLiteral() ast.Expression {
+	set := &ast.SetLiteral{Token: p.currToken}
+	if !p.expectPeek(token.LBRA) {
+		return nil
+	}
+	set.Elements = p.parseExpressionList(token.RBRA)
+	set.EndToken = p.currToken
+	return set
+}
+
 func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	list := []ast.Expression{}
 	
@@ -185,6 +295,7 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.currToken, Function: function}
 	exp.Arguments = p.parseExpressionList(token.RPAR)
+	exp.EndToken = p.currToken
 	
 	return exp
 }
@@ -205,29 +316,37 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	return lit
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	identifiers := []*ast.Identifier{}
+func (p *Parser) parseFunctionParameters() []ast.Pattern {
+	params := []ast.Pattern{}
 	if p.peekTokenIs(token.RPAR) {
 		p.nextToken()
-		return identifiers
+		return params
 	}
 
 	p.nextToken()
-	ident := &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
-	identifiers = append(identifiers, ident)
+	params = append(params, p.parseElementPattern())
 	
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		ident := &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
-		identifiers = append(identifiers, ident)
+		params = append(params, p.parseElementPattern())
+	}
+
+	for i, param := range params {
+		if param == nil {
+			return nil
+		}
+		if _, ok := param.(*ast.DefaultPattern); !ok && i > ast.Required(params) {
+			p.addError(ast.Start(param), "a parameter without a default cannot follow one with a default")
+			return nil
+		}
 	}
 
 	if !p.expectPeek(token.RPAR) {
 		return nil
 	}
 
-	return identifiers
+	return params
 }
 
 func (p *Parser) parseIfExpression() ast.Expression {
@@ -273,10 +392,21 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		}
 		p.nextToken()
 	}
+	block.EndToken = p.currToken
 
 	return block
 }
 
+// Precedence reports how tightly the infix operator t binds, or LOWEST
+// if t is not an infix operator.
+func Precedence(t token.TokenType) int {
+	if p, ok := precedences[t]; ok {
+		return p
+	}
+
+	return LOWEST
+}
+
 func (p *Parser) peekPrecedence() int {
 	if p, ok := precedences[p.peekToken.Type]; ok {
 		return p
@@ -301,12 +431,33 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	}
 
 	precedence := p.currPrecedence()
+	if RightAssociative(expression.Token.Type) {
+		precedence--
+	}
 	p.nextToken()
 	expression.Right = p.parseExpression(precedence)
 	
 	return expression
 }
 
+// parseNotInExpression parses the two-word operator not in, the only
+// place not follows an operand.
+func (p *Parser) parseNotInExpression(left ast.Expression) ast.Expression {
+	expression := &ast.InfixExpression{
+		Token: p.currToken,
+		Left: left,
+	}
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+	expression.Operator = "not in"
+
+	p.nextToken()
+	expression.Right = p.parseExpression(IN)
+
+	return expression
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression {
 		Token: p.currToken,
@@ -315,18 +466,35 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 
 	p.nextToken()
 
-	expression.Right = p.parseExpression(PREFIX)
+	expression.Right = p.parseExpression(PrefixPrecedence(expression.Token.Type))
 
 	return expression
 }
 
+// RightAssociative reports whether the infix operator t groups to the
+// right, as ** does: a ** b ** c is a ** (b ** c).
+func RightAssociative(t token.TokenType) bool {
+	return t == token.POW
+}
+
+// PrefixPrecedence returns the precedence the operand of the prefix
+// operator t is parsed with. Unlike ! and -, not applies to a whole
+// comparison: not a == b is not (a == b).
+func PrefixPrecedence(t token.TokenType) int {
+	if t == token.NOT {
+		return NOT
+	}
+
+	return PREFIX
+}
+
 func (p *Parser) parseIntLiteral() ast.Expression {
 	lit := &ast.IntLiteral{Token: p.currToken}
 	
 	value, err := strconv.ParseInt(p.currToken.Literal, 0, 64)
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as an integer", p.currToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(p.currToken, msg)
 		return nil
 	}
 
@@ -342,6 +510,17 @@ func (p *Parser) Errors() []string {
 	return p.errors
 }
 
+// ParseErrors returns the same errors as Errors along with the tokens
+// they were reported at.
+func (p *Parser) ParseErrors() []ParseError {
+	return p.parseErrors
+}
+
+func (p *Parser) addError(tok token.Token, msg string) {
+	p.errors = append(p.errors, msg)
+	p.parseErrors = append(p.parseErrors, ParseError{Token: tok, Message: msg})
+}
+
 func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
 	p.prefixParseFns[tokenType] = fn
 }
@@ -352,7 +531,7 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 
 func (p *Parser) peekError(t token.TokenType) {
 	msg := fmt.Sprintf("expected next token to be %s but got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.addError(p.peekToken, msg)
 }
 
 func (p *Parser) nextToken() {
@@ -379,10 +558,41 @@ func (p *Parser) parseStatement() ast.Statement {
 	switch p.currToken.Type {
 	case token.DEF:
 		return p.parseDefStatement()
+	case token.STRUCT:
+		return p.parseStructStatement(token.Token{})
+	case token.CLASS:
+		return p.parseClassStatement(token.Token{})
+	case token.EXPORT:
+		export := p.currToken
+		if p.peekTokenIs(token.STRUCT) {
+			p.nextToken()
+			return p.parseStructStatement(export)
+		}
+		if p.peekTokenIs(token.CLASS) {
+			p.nextToken()
+			return p.parseClassStatement(export)
+		}
+		if !p.expectPeek(token.DEF) {
+			return nil
+		}
+		stmt := p.parseDefStatement()
+		if stmt == nil {
+			return nil
+		}
+		stmt.Export = export
+		return stmt
+	case token.IMPORT:
+		return p.parseImportStatement()
 	case token.RET:
 		return p.parseRetStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
 	default:
-		return p.parseExpressionStatement()
+		stmt := p.parseExpressionStatement()
+		if p.peekTokenIs(token.ASSIGN) {
+			return p.parseAssignStatement(stmt.Expression)
+		}
+		return stmt
 	}
 }
 
@@ -411,6 +621,9 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.currToken}
 	
 	stmt.Expression = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.ASSIGN) {
+		return stmt
+	}
 	
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
@@ -422,19 +635,170 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 func (p *Parser) parseDefStatement() *ast.DefStatement {
 	stmt := &ast.DefStatement{Token: p.currToken}
 
+	if p.peekTokenIs(token.LBRACK) || p.peekTokenIs(token.LBRA) {
+		p.nextToken()
+		stmt.Pattern = p.parsePattern()
+		if stmt.Pattern == nil {
+			return nil
+		}
+	} else {
+		if !p.expectPeek(token.ID) {
+			return nil
+		}
+		stmt.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+	}
+
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+	if fl, ok := stmt.Value.(*ast.FunctionLiteral); ok && stmt.Name != nil {
+		fl.Name = stmt.Name.Value
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseAssignStatement parses the rest of an assignment to target, with
+// the '=' as the next token.
+func (p *Parser) parseAssignStatement(target ast.Expression) ast.Statement {
+	p.nextToken()
+	stmt := &ast.AssignStatement{Token: p.currToken, Target: target}
+
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	if _, ok := target.(*ast.MemberExpression); !ok {
+		p.addError(stmt.Token, "can only assign to a member, e.g. a.b = 1")
+		return nil
+	}
+
+	return stmt
+}
+
+// parseStructStatement parses a struct declaration. export is the export
+// keyword before it, if any.
+func (p *Parser) parseStructStatement(export token.Token) ast.Statement {
+	stmt := &ast.StructStatement{Token: p.currToken, Export: export}
+
 	if !p.expectPeek(token.ID) {
 		return nil
 	}
+	stmt.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+	if !p.expectPeek(token.LBRA) {
+		return nil
+	}
 
+	seen := map[string]bool{}
+	for !p.peekTokenIs(token.RBRA) {
+		if !p.expectPeek(token.ID) {
+			return nil
+		}
+		field := &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+		if seen[field.Value] {
+			p.addError(field.Token, fmt.Sprintf("duplicate field %s in struct %s", field.Value, stmt.Name.Value))
+		}
+		seen[field.Value] = true
+		stmt.Fields = append(stmt.Fields, field)
+
+		if !p.peekTokenIs(token.RBRA) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	stmt.EndToken = p.currToken
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseClassStatement parses a class declaration. export is the export
+// keyword before it, if any.
+func (p *Parser) parseClassStatement(export token.Token) ast.Statement {
+	stmt := &ast.ClassStatement{Token: p.currToken, Export: export}
+
+	if !p.expectPeek(token.ID) {
+		return nil
+	}
 	stmt.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
 
-	if !p.expectPeek(token.ASSIGN) {
+	if p.peekTokenIs(token.LT) {
+		p.nextToken()
+		if !p.expectPeek(token.ID) {
+			return nil
+		}
+		stmt.Super = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+	}
+
+	if !p.expectPeek(token.LBRA) {
 		return nil
 	}
 
+	seen := map[string]bool{}
+	for !p.peekTokenIs(token.RBRA) {
+		if p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+			continue
+		}
+		if !p.expectPeek(token.ID) {
+			return nil
+		}
+		method := &ast.FunctionLiteral{Token: p.currToken, Name: p.currToken.Literal}
+		if seen[method.Name] {
+			p.addError(method.Token, fmt.Sprintf("duplicate method %s in class %s", method.Name, stmt.Name.Value))
+		}
+		seen[method.Name] = true
+
+		if !p.expectPeek(token.LPAR) {
+			return nil
+		}
+		method.Parameters = p.parseFunctionParameters()
+		if !p.expectPeek(token.LBRA) {
+			return nil
+		}
+		method.Body = p.parseBlockStatement()
+		stmt.Methods = append(stmt.Methods, method)
+	}
 	p.nextToken()
+	stmt.EndToken = p.currToken
 
-	stmt.Value = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseImportStatement() ast.Statement {
+	stmt := &ast.ImportStatement{Token: p.currToken}
+
+	if !p.expectPeek(token.STR) {
+		return nil
+	}
+	stmt.Path = &ast.StrLiteral{Token: p.currToken, Value: p.currToken.Literal}
+
+	if !p.expectPeek(token.AS) {
+		return nil
+	}
+	if !p.expectPeek(token.ID) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
 
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
@@ -457,6 +821,64 @@ func (p *Parser) parseRetStatement() *ast.RetStatement {
 	return stmt
 }
 
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.currToken}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseTryExpression parses try { ... } catch (e) { ... } finally { ... }.
+// The catch parameter is optional, and so is either block, but not both.
+func (p *Parser) parseTryExpression() ast.Expression {
+	expression := &ast.TryExpression{Token: p.currToken}
+
+	if !p.expectPeek(token.LBRA) {
+		return nil
+	}
+	expression.Body = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+		if p.peekTokenIs(token.LPAR) {
+			p.nextToken()
+			if !p.expectPeek(token.ID) {
+				return nil
+			}
+			expression.Param = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+			if !p.expectPeek(token.RPAR) {
+				return nil
+			}
+		}
+		if !p.expectPeek(token.LBRA) {
+			return nil
+		}
+		expression.Catch = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+		if !p.expectPeek(token.LBRA) {
+			return nil
+		}
+		expression.Finally = p.parseBlockStatement()
+	}
+
+	if expression.Catch == nil && expression.Finally == nil {
+		p.addError(expression.Token, "try needs a catch or a finally block")
+		return nil
+	}
+
+	return expression
+}
+
 func (p *Parser) currTokenIs(t token.TokenType) bool {
 	return p.currToken.Type == t
 }
@@ -477,14 +899,38 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
 	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.addError(p.currToken, msg)
 }
 
+// parseGroupedExpression parses a parenthesized expression, or a tuple
+// if the parentheses are empty or hold a comma.
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	start := p.currToken
+	if p.peekTokenIs(token.RPAR) {
+		p.nextToken()
+		return &ast.TupleLiteral{Token: start, Elements: []ast.Expression{}, EndToken: p.currToken}
+	}
 	p.nextToken()
 
 	exp := p.parseExpression(LOWEST)
 
+	if p.peekTokenIs(token.COMMA) {
+		tuple := &ast.TupleLiteral{Token: start, Elements: []ast.Expression{exp}}
+		for p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+			if p.peekTokenIs(token.RPAR) {
+				break
+			}
+			p.nextToken()
+			tuple.Elements = append(tuple.Elements, p.parseExpression(LOWEST))
+		}
+		if !p.expectPeek(token.RPAR) {
+			return nil
+		}
+		tuple.EndToken = p.currToken
+		return tuple
+	}
+
 	if !p.expectPeek(token.RPAR) {
 		return nil
 	}
@@ -494,4 +940,173 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.currToken, Value: p.currTokenIs(token.TRUE)}
-}
\ No newline at end of file
+}
+
+// parseMatchExpression parses match subject { pattern if guard => body, ... }.
+// The guard is optional and a comma may follow the last arm.
+func (p *Parser) parseMatchExpression() ast.Expression {
+	exp := &ast.MatchExpression{Token: p.currToken}
+
+	p.nextToken()
+	exp.Subject = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.LBRA) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRA) {
+		p.nextToken()
+		arm := &ast.MatchArm{Pattern: p.parsePattern()}
+		if arm.Pattern == nil {
+			return nil
+		}
+
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+			p.nextToken()
+			arm.Guard = p.parseExpression(LOWEST)
+		}
+		if !p.expectPeek(token.ARROW) {
+			return nil
+		}
+		p.nextToken()
+		arm.Body = p.parseExpression(LOWEST)
+		exp.Arms = append(exp.Arms, arm)
+
+		if !p.peekTokenIs(token.RBRA) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	exp.EndToken = p.currToken
+
+	return exp
+}
+
+// parsePattern parses the pattern starting at the current token.
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.currToken.Type {
+	case token.ID:
+		return &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+	case token.INT, token.STR, token.TRUE, token.FALSE:
+		return &ast.LiteralPattern{Value: p.parsePatternLiteral()}
+	case token.MINUS:
+		minus := &ast.PrefixExpression{Token: p.currToken, Operator: "-"}
+		if !p.expectPeek(token.INT) {
+			return nil
+		}
+		minus.Right = p.parseIntLiteral()
+		return &ast.LiteralPattern{Value: minus}
+	case token.LBRACK:
+		return p.parseArrPattern()
+	case token.LBRA:
+		return p.parseHashPattern()
+	default:
+		p.addError(p.currToken, fmt.Sprintf("expected a pattern but got %s instead", p.currToken.Type))
+		return nil
+	}
+}
+
+// parseElementPattern parses a pattern that may be followed by a default
+// value, as an array element, hash value or function parameter can.
+func (p *Parser) parseElementPattern() ast.Pattern {
+	pattern := p.parsePattern()
+	if pattern == nil || !p.peekTokenIs(token.ASSIGN) {
+		return pattern
+	}
+
+	p.nextToken()
+	dp := &ast.DefaultPattern{Token: p.currToken, Pattern: pattern}
+	p.nextToken()
+	dp.Default = p.parseExpression(LOWEST)
+
+	return dp
+}
+
+// parsePatternLiteral parses the int, string or boolean literal at the
+// current token.
+func (p *Parser) parsePatternLiteral() ast.Expression {
+	switch p.currToken.Type {
+	case token.INT:
+		return p.parseIntLiteral()
+	case token.STR:
+		return p.parseStrLiteral()
+	default:
+		return p.parseBoolean()
+	}
+}
+
+func (p *Parser) parseArrPattern() ast.Pattern {
+	pattern := &ast.ArrPattern{Token: p.currToken}
+
+	for !p.peekTokenIs(token.RBRACK) {
+		p.nextToken()
+		if p.currTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.ID) {
+				return nil
+			}
+			pattern.Rest = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+			if !p.peekTokenIs(token.RBRACK) {
+				p.addError(p.peekToken, "a rest pattern must come last")
+				return nil
+			}
+			break
+		}
+
+		el := p.parseElementPattern()
+		if el == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, el)
+
+		if !p.peekTokenIs(token.RBRACK) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	pattern.EndToken = p.currToken
+
+	return pattern
+}
+
+func (p *Parser) parseHashPattern() ast.Pattern {
+	pattern := &ast.HashPattern{Token: p.currToken, Values: map[ast.Expression]ast.Pattern{}}
+
+	for !p.peekTokenIs(token.RBRA) {
+		p.nextToken()
+
+		var key ast.Expression
+		var value ast.Pattern
+		switch p.currToken.Type {
+		case token.ID:
+			if p.peekTokenIs(token.COLON) {
+				p.addError(p.currToken, fmt.Sprintf("expected a literal key but got %s instead", p.currToken.Type))
+				return nil
+			}
+			key = &ast.StrLiteral{Token: p.currToken, Value: p.currToken.Literal}
+			value = p.parseElementPattern()
+		case token.INT, token.STR, token.TRUE, token.FALSE:
+			key = p.parsePatternLiteral()
+			if !p.expectPeek(token.COLON) {
+				return nil
+			}
+			p.nextToken()
+			value = p.parseElementPattern()
+		default:
+			p.addError(p.currToken, fmt.Sprintf("expected a literal key but got %s instead", p.currToken.Type))
+			return nil
+		}
+		if value == nil {
+			return nil
+		}
+		pattern.Keys = append(pattern.Keys, key)
+		pattern.Values[key] = value
+
+		if !p.peekTokenIs(token.RBRA) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	pattern.EndToken = p.currToken
+
+	return pattern
+}
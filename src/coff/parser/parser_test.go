@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"coff-src/src/coff/ast"
 	"coff-src/src/coff/lexer"
@@ -445,6 +446,82 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"lib.f(x) + -a.b",
+			"((lib.f)(x) + (-(a.b)))",
+		},
+		{
+			"a.b[0].c",
+			"(((a.b)[0]).c)",
+		},
+		{
+			"a.b is C == true",
+			"(((a.b) is C) == true)",
+		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a && b || c",
+			"((a && b) || c)",
+		},
+		{
+			"a < b && c == d",
+			"((a < b) && (c == d))",
+		},
+		{
+			"not a == b and c",
+			"((not (a == b)) and c)",
+		},
+		{
+			"not a or not b",
+			"((not a) or (not b))",
+		},
+		{
+			"a in b == c not in d",
+			"((a in b) == (c not in d))",
+		},
+		{
+			"not a in b | c",
+			"(not (a in (b | c)))",
+		},
+		{
+			"a < b in c",
+			"((a < b) in c)",
+		},
+		{
+			"!a && b",
+			"((!a) && b)",
+		},
+		{
+			"a <= b == c >= d",
+			"((a <= b) == (c >= d))",
+		},
+		{
+			"a ** b ** c",
+			"(a ** (b ** c))",
+		},
+		{
+			"-a ** b * c",
+			"((-(a ** b)) * c)",
+		},
+		{
+			"a % b + c",
+			"((a % b) + c)",
+		},
+		{
+			"a div b * c - d mod e",
+			"(((a div b) * c) - (d mod e))",
+		},
+		{
+			"a | b ^ c & d << e + f",
+			"(a | (b ^ (c & (d << (e + f)))))",
+		},
+		{
+			"~a & b < c",
+			"(((~a) & b) < c)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -761,8 +838,8 @@ func TestFunctionLiteralParsing(t *testing.T) {
 		t.Fatalf("function literal parameters are wrong. want 2, got=%d\n", len(function.Parameters))
 	}
 
-	testLiteralExpression(t, function.Parameters[0], "x")
-	testLiteralExpression(t, function.Parameters[1], "y")
+	testLiteralExpression(t, function.Parameters[0].(ast.Expression), "x")
+	testLiteralExpression(t, function.Parameters[1].(ast.Expression), "y")
 
 	if len(function.Body.Statements) != 1 {
 		t.Fatalf("function.Body.Statements does not have 1 statement. got=%d\n", len(function.Body.Statements))
@@ -799,7 +876,7 @@ func TestFunctionParameterParsing(t *testing.T) {
 			t.Errorf("length parameters wrong. want %d, got=%d\n", len(tt.expectedParams), len(function.Parameters))
 		}
 		for i, ident := range tt.expectedParams {
-			testLiteralExpression(t, function.Parameters[i], ident)
+			testLiteralExpression(t, function.Parameters[i].(ast.Expression), ident)
 		}
 	}
 }
@@ -959,4 +1036,549 @@ func TestParsingIdxExpressions(t *testing.T) {
 	if !testInfixExpression(t, indexExp.Index, 1, "+", 1) {
 		return
 	}
-}
\ No newline at end of file
+}
+
+func TestImportStatement(t *testing.T) {
+	l := lexer.New(`import "lib/math.coff" as math;`)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ImportStatement)
+	if !ok {
+		t.Fatalf("stmt is not *ast.ImportStatement. got=%T", program.Statements[0])
+	}
+	if stmt.Path.Value != "lib/math.coff" || stmt.Name.Value != "math" {
+		t.Errorf("wrong import: %s", stmt.String())
+	}
+}
+
+func TestExportedDefStatement(t *testing.T) {
+	l := lexer.New("export def x = 1; def y = 2;")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	x := program.Statements[0].(*ast.DefStatement)
+	y := program.Statements[1].(*ast.DefStatement)
+	if !x.Exported() || y.Exported() {
+		t.Errorf("wrong exports: x=%t, y=%t", x.Exported(), y.Exported())
+	}
+	if x.String() != "export def x = 1;" {
+		t.Errorf("wrong string: %q", x.String())
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import lib as lib`, "expected next token to be STR but got ID instead"},
+		{`import "lib.coff" lib`, "expected next token to be AS but got ID instead"},
+		{`export x = 1`, "expected next token to be DEF but got ID instead"},
+		{`a.1`, "expected next token to be ID but got INT instead"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("wrong errors for %q. expected %q first, got=%v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestAssignStatement(t *testing.T) {
+	p := New(lexer.New(`user.address.city = "Oslo";`))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.AssignStatement)
+	if !ok {
+		t.Fatalf("stmt is not *ast.AssignStatement. got=%T", program.Statements[0])
+	}
+	target, ok := stmt.Target.(*ast.MemberExpression)
+	if !ok {
+		t.Fatalf("stmt.Target is not *ast.MemberExpression. got=%T", stmt.Target)
+	}
+	if target.Member.Value != "city" {
+		t.Errorf("target.Member is not %q. got=%q", "city", target.Member.Value)
+	}
+	if stmt.String() != "((user.address).city) = Oslo;" {
+		t.Errorf("wrong string: %q", stmt.String())
+	}
+}
+
+func TestAssignErrors(t *testing.T) {
+	tests := []string{
+		`x = 1`,
+		`a[0] = 1`,
+		`f(x) = 1`,
+	}
+
+	for _, input := range tests {
+		p := New(lexer.New(input))
+		program := p.ParseProgram()
+		expected := "can only assign to a member, e.g. a.b = 1"
+		if len(p.Errors()) != 1 || p.Errors()[0] != expected {
+			t.Errorf("wrong errors for %q. expected %q, got=%v", input, expected, p.Errors())
+		}
+		if len(program.Statements) != 0 {
+			t.Errorf("expected no statements for %q, got=%d", input, len(program.Statements))
+		}
+	}
+}
+
+func TestStructStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		name     string
+		fields   []string
+		exported bool
+	}{
+		{"struct Point { x, y }", "Point", []string{"x", "y"}, false},
+		{"struct Point { x, y, };", "Point", []string{"x", "y"}, false},
+		{"struct Empty {}", "Empty", nil, false},
+		{"export struct Pair { left, right }", "Pair", []string{"left", "right"}, true},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.StructStatement)
+		if !ok {
+			t.Fatalf("stmt is not *ast.StructStatement. got=%T", program.Statements[0])
+		}
+		if stmt.Name.Value != tt.name {
+			t.Errorf("stmt.Name is not %q. got=%q", tt.name, stmt.Name.Value)
+		}
+		if len(stmt.Fields) != len(tt.fields) {
+			t.Fatalf("wrong number of fields. expected=%d, got=%d", len(tt.fields), len(stmt.Fields))
+		}
+		for i, f := range tt.fields {
+			if stmt.Fields[i].Value != f {
+				t.Errorf("field %d is not %q. got=%q", i, f, stmt.Fields[i].Value)
+			}
+		}
+		if stmt.Exported() != tt.exported {
+			t.Errorf("stmt.Exported() is not %t", tt.exported)
+		}
+	}
+}
+
+func TestStructErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"struct { x }", "expected next token to be ID but got { instead"},
+		{"struct Point x, y", "expected next token to be { but got ID instead"},
+		{"struct Point { x y }", "expected next token to be , but got ID instead"},
+		{"struct Point { x, 1 }", "expected next token to be ID but got INT instead"},
+		{"struct Point { x, y, x }", "duplicate field x in struct Point"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("wrong errors for %q. expected %q first, got=%v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestClassStatement(t *testing.T) {
+	input := `
+class Dog < Animal {
+	init(name) { self.name = name; }
+	speak() { "woof" }
+}
+`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ClassStatement)
+	if !ok {
+		t.Fatalf("stmt is not *ast.ClassStatement. got=%T", program.Statements[0])
+	}
+	if stmt.Name.Value != "Dog" || stmt.Super == nil || stmt.Super.Value != "Animal" {
+		t.Errorf("wrong class header: %q", stmt.String())
+	}
+	if len(stmt.Methods) != 2 {
+		t.Fatalf("wrong number of methods. got=%d", len(stmt.Methods))
+	}
+	init := stmt.Methods[0]
+	if init.Name != "init" || len(init.Parameters) != 1 || init.Parameters[0].String() != "name" {
+		t.Errorf("wrong init method: %q", init.String())
+	}
+	if stmt.String() != "class Dog < Animal { init(name) (self.name) = name; speak() woof }" {
+		t.Errorf("wrong string: %q", stmt.String())
+	}
+}
+
+func TestClassErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"class { }", "expected next token to be ID but got { instead"},
+		{"class A < { }", "expected next token to be ID but got { instead"},
+		{"class A { def x = 1; }", "expected next token to be ID but got DEF instead"},
+		{"class A { f { 1 } }", "expected next token to be ( but got { instead"},
+		{"class A { f() {} f() {} }", "duplicate method f in class A"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("wrong errors for %q. expected %q first, got=%v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	input := `match x { 0 => "zero", -1 => a, [h, ...t] if h > 0 => h, {"id": id, 1: _} => id, _ => x }`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *ast.MatchExpression. got=%T", stmt.Expression)
+	}
+	if len(exp.Arms) != 5 {
+		t.Fatalf("wrong number of arms. got=%d", len(exp.Arms))
+	}
+
+	if _, ok := exp.Arms[1].Pattern.(*ast.LiteralPattern); !ok {
+		t.Errorf("arm 1 pattern is not *ast.LiteralPattern. got=%T", exp.Arms[1].Pattern)
+	}
+	arr, ok := exp.Arms[2].Pattern.(*ast.ArrPattern)
+	if !ok || len(arr.Elements) != 1 || arr.Rest == nil || arr.Rest.Value != "t" {
+		t.Errorf("wrong array pattern: %v", exp.Arms[2].Pattern)
+	}
+	if exp.Arms[2].Guard == nil {
+		t.Errorf("arm 2 has no guard")
+	}
+	hash, ok := exp.Arms[3].Pattern.(*ast.HashPattern)
+	if !ok || len(hash.Keys) != 2 {
+		t.Errorf("wrong hash pattern: %v", exp.Arms[3].Pattern)
+	}
+
+	expected := "match x { 0 => zero, (-1) => a, [h, ...t] if (h > 0) => h, {id: id, 1: _} => id, _ => x }"
+	if exp.String() != expected {
+		t.Errorf("wrong string. expected=%q, got=%q", expected, exp.String())
+	}
+}
+
+func TestMatchErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match x { 1 2 }", "expected next token to be => but got INT instead"},
+		{"match x { 1 => 2 3 => 4 }", "expected next token to be , but got INT instead"},
+		{"match x { a + 1 => 2 }", "expected next token to be => but got + instead"},
+		{"match x { (a) => 2 }", "expected a pattern but got ( instead"},
+		{"match x { [...a, b] => 2 }", "a rest pattern must come last"},
+		{"match x { {a: 1} => 2 }", "expected a literal key but got ID instead"},
+		{"match x 1", "expected next token to be { but got INT instead"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("wrong errors for %q. expected %q first, got=%v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestDestructuringDef(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		names    []string
+	}{
+		{"def [a, b, ...rest] = arr;", "def [a, b, ...rest] = arr;", []string{"a", "b", "rest"}},
+		{"def {name, age} = person;", "def {name, age} = person;", []string{"name", "age"}},
+		{`def {"user": {name}, id = 0} = x;`, "def {user: {name}, id = 0} = x;", []string{"name", "id"}},
+		{"def [a, [b, _], c = 1] = x;", "def [a, [b, _], c = 1] = x;", []string{"a", "b", "c"}},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.DefStatement)
+		if !ok {
+			t.Fatalf("stmt is not *ast.DefStatement. got=%T", program.Statements[0])
+		}
+		if stmt.Name != nil || stmt.Pattern == nil {
+			t.Errorf("%q: expected a pattern and no name", tt.input)
+		}
+		if stmt.String() != tt.expected {
+			t.Errorf("wrong string. expected=%q, got=%q", tt.expected, stmt.String())
+		}
+
+		names := []string{}
+		for _, id := range ast.Bindings(stmt.Pattern) {
+			names = append(names, id.Value)
+		}
+		if strings.Join(names, ",") != strings.Join(tt.names, ",") {
+			t.Errorf("%q: wrong bindings. expected=%v, got=%v", tt.input, tt.names, names)
+		}
+	}
+}
+
+func TestParameterPatterns(t *testing.T) {
+	p := New(lexer.New("fun([a, ...b], {c}, d = 1, e = d) { a }"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	fn := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if len(fn.Parameters) != 4 {
+		t.Fatalf("wrong number of parameters. got=%d", len(fn.Parameters))
+	}
+	if _, ok := fn.Parameters[0].(*ast.ArrPattern); !ok {
+		t.Errorf("parameter 0 is not *ast.ArrPattern. got=%T", fn.Parameters[0])
+	}
+	if _, ok := fn.Parameters[1].(*ast.HashPattern); !ok {
+		t.Errorf("parameter 1 is not *ast.HashPattern. got=%T", fn.Parameters[1])
+	}
+	if _, ok := fn.Parameters[2].(*ast.DefaultPattern); !ok {
+		t.Errorf("parameter 2 is not *ast.DefaultPattern. got=%T", fn.Parameters[2])
+	}
+	if ast.Required(fn.Parameters) != 2 {
+		t.Errorf("wrong number of required parameters. got=%d", ast.Required(fn.Parameters))
+	}
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fun(a = 1, b) { b }", "a parameter without a default cannot follow one with a default"},
+		{"def [a, b = ] = x", "no prefix parse function for ] found"},
+		{"def {a: b} = x", "expected a literal key but got ID instead"},
+		{"def [a] x", "expected next token to be = but got ID instead"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("wrong errors for %q. expected %q first, got=%v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestTryExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		param    string
+	}{
+		{"try { f(); } catch (e) { g(e); }", "try f() catch(e) g(e)", "e"},
+		{"try { f(); } catch { 1 }", "try f() catch 1", ""},
+		{"try { f(); } finally { close(); }", "try f() finally close()", ""},
+		{"try { f(); } catch (err) { 1 } finally { 2 }", "try f() catch(err) 1 finally 2", "err"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.TryExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not *ast.TryExpression. got=%T", stmt.Expression)
+		}
+		if exp.String() != tt.expected {
+			t.Errorf("wrong string. expected=%q, got=%q", tt.expected, exp.String())
+		}
+		param := ""
+		if exp.Param != nil {
+			param = exp.Param.Value
+		}
+		if param != tt.param {
+			t.Errorf("wrong catch parameter. expected=%q, got=%q", tt.param, param)
+		}
+	}
+}
+
+func TestThrowStatement(t *testing.T) {
+	p := New(lexer.New(`throw error("bad");`))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ThrowStatement)
+	if !ok {
+		t.Fatalf("stmt is not *ast.ThrowStatement. got=%T", program.Statements[0])
+	}
+	if stmt.String() != "throw error(bad);" {
+		t.Errorf("wrong string. got=%q", stmt.String())
+	}
+}
+
+func TestTryErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { 1 }", "try needs a catch or a finally block"},
+		{"try 1 catch { 2 }", "expected next token to be { but got INT instead"},
+		{"try { 1 } catch (1) { 2 }", "expected next token to be ID but got INT instead"},
+		{"try { 1 } catch (e { 2 }", "expected next token to be ) but got { instead"},
+		{"try { 1 } finally 2", "expected next token to be { but got INT instead"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("wrong errors for %q. expected %q first, got=%v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestTupleLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		length   int
+	}{
+		{"(1, 2)", "(1, 2)", 2},
+		{"(1, a + b,)", "(1, (a + b))", 2},
+		{"(1,)", "(1,)", 1},
+		{"()", "()", 0},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		tuple, ok := stmt.Expression.(*ast.TupleLiteral)
+		if !ok {
+			t.Fatalf("%q: stmt.Expression is not *ast.TupleLiteral. got=%T", tt.input, stmt.Expression)
+		}
+		if len(tuple.Elements) != tt.length {
+			t.Errorf("%q: wrong number of elements. got=%d", tt.input, len(tuple.Elements))
+		}
+		if tuple.String() != tt.expected {
+			t.Errorf("wrong string. expected=%q, got=%q", tt.expected, tuple.String())
+		}
+	}
+
+	p := New(lexer.New("(1, 2"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "expected next token to be ) but got EOF instead" {
+		t.Errorf("wrong errors for unclosed tuple: %v", p.Errors())
+	}
+}
+
+func TestSliceExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a[1:2]", "(a[1:2])"},
+		{"a[:j]", "(a[:j])"},
+		{"a[i + 1:]", "(a[(i + 1):])"},
+		{"a[:]", "(a[:])"},
+		{"a[::-1]", "(a[::(-1)])"},
+		{"a[1:2:3]", "(a[1:2:3])"},
+		{"a[1::]", "(a[1:])"},
+		{"a[1:][0]", "((a[1:])[0])"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if stmt.Expression.String() != tt.expected {
+			t.Errorf("wrong string. expected=%q, got=%q", tt.expected, stmt.Expression.String())
+		}
+	}
+
+	p := New(lexer.New("a[1:2"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "expected next token to be ] but got EOF instead" {
+		t.Errorf("wrong errors for unclosed slice: %v", p.Errors())
+	}
+}
+
+func TestNotWithoutIn(t *testing.T) {
+	p := New(lexer.New("a not b"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "expected next token to be IN but got ID instead" {
+		t.Errorf("wrong errors for not without in: %v", p.Errors())
+	}
+}
+
+func TestSetLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		length   int
+	}{
+		{"#{1, 2}", "#{1, 2}", 2},
+		{"#{1, a + b}", "#{1, (a + b)}", 2},
+		{"#{}", "#{}", 0},
+		{"x in #{1} | s", "(x in (#{1} | s))", -1},
+		{"x not in #{1}", "(x not in #{1})", -1},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if stmt.Expression.String() != tt.expected {
+			t.Errorf("wrong string. expected=%q, got=%q", tt.expected, stmt.Expression.String())
+		}
+		if tt.length < 0 {
+			continue
+		}
+		set, ok := stmt.Expression.(*ast.SetLiteral)
+		if !ok {
+			t.Fatalf("%q: stmt.Expression is not *ast.SetLiteral. got=%T", tt.input, stmt.Expression)
+		}
+		if len(set.Elements) != tt.length {
+			t.Errorf("%q: wrong number of elements. got=%d", tt.input, len(set.Elements))
+		}
+	}
+
+	p := New(lexer.New("#[1]"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "expected next token to be { but got [ instead" {
+		t.Errorf("wrong errors for # without {: %v", p.Errors())
+	}
+}
@@ -0,0 +1,140 @@
+package profile
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// WritePprof writes the profile in the gzipped protocol buffer format
+// read by `go tool pprof`. Its frames are CoffLang functions; each
+// sample is a call stack with the calls ending in it, the nanoseconds
+// spent at its top and the values allocated there.
+func (p *Profiler) WritePprof(w io.Writer) error {
+	indices := map[string]int64{}
+	table := []string{}
+	str := func(s string) int64 {
+		if i, ok := indices[s]; ok {
+			return i
+		}
+		indices[s] = int64(len(table))
+		table = append(table, s)
+		return indices[s]
+	}
+	str("")
+
+	var profile encoder
+	valueType := func(typ, unit string) []byte {
+		var vt encoder
+		vt.int64(1, str(typ))
+		vt.int64(2, str(unit))
+		return vt
+	}
+
+	// Profile.sample_type
+	profile.message(1, valueType("calls", "count"))
+	profile.message(1, valueType("time", "nanoseconds"))
+	profile.message(1, valueType("allocations", "count"))
+
+	// Profile.sample, with the innermost location first
+	for _, s := range p.sortedSamples() {
+		var sample encoder
+		locations := []uint64{}
+		for i := len(s.stack) - 1; i >= 0; i-- {
+			locations = append(locations, s.stack[i].id)
+		}
+		sample.packed(1, locations)
+		sample.packed(2, []uint64{uint64(s.calls), uint64(s.time.Nanoseconds()), uint64(s.allocs)})
+		profile.message(2, sample)
+	}
+
+	// Profile.location, one per function
+	for _, fn := range p.order {
+		var line encoder
+		line.uint64(1, fn.id)
+		line.int64(2, int64(fn.Line))
+
+		var location encoder
+		location.uint64(1, fn.id)
+		location.message(4, line)
+		profile.message(4, location)
+	}
+
+	// Profile.function
+	for _, fn := range p.order {
+		var function encoder
+		function.uint64(1, fn.id)
+		function.int64(2, str(fn.Name))
+		function.int64(3, str(fn.Name))
+		function.int64(4, str(fn.File))
+		function.int64(5, int64(fn.Line))
+		profile.message(5, function)
+	}
+
+	timeNanos := p.start.UnixNano()
+	durationNanos := p.total().Nanoseconds()
+	periodType := valueType("time", "nanoseconds")
+	defaultSampleType := str("time")
+
+	// Profile.string_table, after every string has been added
+	for _, s := range table {
+		profile.bytes(6, []byte(s))
+	}
+	profile.int64(9, timeNanos)
+	profile.int64(10, durationNanos)
+	profile.message(11, periodType)
+	profile.int64(12, 1)
+	profile.int64(14, defaultSampleType)
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(profile); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// encoder appends protocol buffer fields to itself.
+type encoder []byte
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+func (e *encoder) varint(v uint64) {
+	for v >= 0x80 {
+		*e = append(*e, byte(v)|0x80)
+		v >>= 7
+	}
+	*e = append(*e, byte(v))
+}
+
+func (e *encoder) key(field int, wire int) {
+	e.varint(uint64(field)<<3 | uint64(wire))
+}
+
+func (e *encoder) uint64(field int, v uint64) {
+	e.key(field, wireVarint)
+	e.varint(v)
+}
+
+func (e *encoder) int64(field int, v int64) {
+	e.uint64(field, uint64(v))
+}
+
+func (e *encoder) bytes(field int, b []byte) {
+	e.key(field, wireBytes)
+	e.varint(uint64(len(b)))
+	*e = append(*e, b...)
+}
+
+func (e *encoder) message(field int, m []byte) {
+	e.bytes(field, m)
+}
+
+func (e *encoder) packed(field int, values []uint64) {
+	var p encoder
+	for _, v := range values {
+		p.varint(v)
+	}
+	e.bytes(field, p)
+}
@@ -0,0 +1,234 @@
+// Package profile measures where a CoffLang program spends its time. It
+// counts the calls to each function, the time spent in it and in what it
+// calls, and the values it allocates, and writes the results as a text
+// report, as a pprof profile or as folded stacks for flame graphs.
+package profile
+
+import (
+	"coff-src/src/coff/ast"
+	"coff-src/src/coff/eval"
+	"coff-src/src/coff/object"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Names of frames that are not named functions. pprof strips anything in
+// angle brackets from function names, so these use square brackets.
+const (
+	MAIN      = "[main]"
+	ANONYMOUS = "[anonymous]"
+)
+
+// Function holds the measurements of one function literal. Closures made
+// from the same literal count as one function.
+type Function struct {
+	Name      string
+	File      string // the file the function is defined in
+	Line      int
+	Calls     int
+	Inclusive time.Duration // time spent in the function and its callees
+	Exclusive time.Duration // time spent in the function itself
+	Allocs    int           // strings, arrays, hashes and functions created
+
+	id     uint64
+	active int // calls of the function currently on the stack
+}
+
+// sample holds the measurements of one call stack.
+type sample struct {
+	stack  []*Function // outermost first
+	calls  int64
+	allocs int64
+	time   time.Duration
+}
+
+type frame struct {
+	fn       *Function
+	sample   *sample
+	start    time.Time
+	children time.Duration
+}
+
+type Profiler struct {
+	// File names the program's source in reports and profiles. Functions
+	// of imported modules are reported with their own files.
+	File string
+
+	now       func() time.Time
+	start     time.Time
+	functions map[*ast.BlockStatement]*Function
+	order     []*Function
+	samples   map[string]*sample
+	stack     []*frame
+}
+
+func New(file string) *Profiler {
+	return &Profiler{File: file, now: time.Now}
+}
+
+// Run evaluates program in env and records its profile.
+func (p *Profiler) Run(program *ast.Program, env *object.Env) object.Object {
+	p.functions = map[*ast.BlockStatement]*Function{}
+	p.order = nil
+	p.samples = map[string]*sample{}
+	p.stack = nil
+
+	remove := eval.AddHooks(&eval.Hooks{Call: p.call, Return: p.ret, Alloc: p.alloc})
+	defer remove()
+
+	p.start = p.now()
+	p.enter(p.function(nil, MAIN, p.File, 1))
+	result := eval.Eval(program, env)
+	p.leave()
+
+	return result
+}
+
+func (p *Profiler) function(body *ast.BlockStatement, name, file string, line int) *Function {
+	if fn, ok := p.functions[body]; ok {
+		return fn
+	}
+
+	fn := &Function{Name: name, File: file, Line: line, id: uint64(len(p.order) + 1)}
+	p.functions[body] = fn
+	p.order = append(p.order, fn)
+	return fn
+}
+
+func (p *Profiler) call(fn *object.Function, args []object.Object, env *object.Env) {
+	name := fn.Name
+	if name == "" {
+		name = ANONYMOUS
+	}
+	file := p.File
+	if path := fn.Env.Path(); path != "" {
+		file = eval.DisplayPath(path)
+	}
+	p.enter(p.function(fn.Body, name, file, fn.Body.Token.Line))
+}
+
+func (p *Profiler) ret(fn *object.Function, result object.Object) {
+	p.leave()
+}
+
+func (p *Profiler) alloc(obj object.Object) {
+	top := p.stack[len(p.stack)-1]
+	top.fn.Allocs++
+	top.sample.allocs++
+}
+
+func (p *Profiler) enter(fn *Function) {
+	fn.Calls++
+	fn.active++
+
+	stack := []*Function{}
+	ids := []string{}
+	for _, f := range p.stack {
+		stack = append(stack, f.fn)
+		ids = append(ids, fmt.Sprint(f.fn.id))
+	}
+	stack = append(stack, fn)
+	ids = append(ids, fmt.Sprint(fn.id))
+
+	key := strings.Join(ids, ";")
+	s, ok := p.samples[key]
+	if !ok {
+		s = &sample{stack: stack}
+		p.samples[key] = s
+	}
+	s.calls++
+
+	p.stack = append(p.stack, &frame{fn: fn, sample: s, start: p.now()})
+}
+
+func (p *Profiler) leave() {
+	f := p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+
+	elapsed := p.now().Sub(f.start)
+	exclusive := elapsed - f.children
+	f.fn.Exclusive += exclusive
+	f.sample.time += exclusive
+	if f.fn.active == 1 {
+		f.fn.Inclusive += elapsed
+	}
+	f.fn.active--
+
+	if len(p.stack) != 0 {
+		p.stack[len(p.stack)-1].children += elapsed
+	}
+}
+
+// Functions returns the measured functions, those taking the most time
+// first.
+func (p *Profiler) Functions() []*Function {
+	functions := append([]*Function{}, p.order...)
+	sort.SliceStable(functions, func(i, j int) bool {
+		return functions[i].Inclusive > functions[j].Inclusive
+	})
+	return functions
+}
+
+// total is the time the whole program took.
+func (p *Profiler) total() time.Duration {
+	if len(p.order) == 0 {
+		return 0
+	}
+	return p.order[0].Inclusive
+}
+
+// sortedSamples returns the samples ordered by their stacks' names.
+func (p *Profiler) sortedSamples() []*sample {
+	samples := []*sample{}
+	for _, s := range p.samples {
+		samples = append(samples, s)
+	}
+	sort.Slice(samples, func(i, j int) bool {
+		return folded(samples[i]) < folded(samples[j])
+	})
+	return samples
+}
+
+// WriteReport writes a table of the measured functions.
+func (p *Profiler) WriteReport(w io.Writer) error {
+	total := p.total()
+	percent := func(d time.Duration) float64 {
+		if total == 0 {
+			return 0
+		}
+		return 100 * float64(d) / float64(total)
+	}
+
+	fmt.Fprintf(w, "%8s %12s %7s %12s %7s %8s  %s\n", "calls", "flat", "flat%", "cum", "cum%", "allocs", "function")
+	for _, fn := range p.Functions() {
+		_, err := fmt.Fprintf(w, "%8d %12s %6.2f%% %12s %6.2f%% %8d  %s %s:%d\n",
+			fn.Calls, fn.Exclusive, percent(fn.Exclusive), fn.Inclusive, percent(fn.Inclusive),
+			fn.Allocs, fn.Name, fn.File, fn.Line)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteFolded writes one line per call stack with the nanoseconds spent
+// at its top, in the format flame graph tools read.
+func (p *Profiler) WriteFolded(w io.Writer) error {
+	for _, s := range p.sortedSamples() {
+		if _, err := fmt.Fprintf(w, "%s %d\n", folded(s), s.time.Nanoseconds()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func folded(s *sample) string {
+	names := []string{}
+	for _, fn := range s.stack {
+		names = append(names, fn.Name)
+	}
+	return strings.Join(names, ";")
+}
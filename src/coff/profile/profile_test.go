@@ -0,0 +1,154 @@
+package profile
+
+import (
+	"bytes"
+	"coff-src/src/coff/lexer"
+	"coff-src/src/coff/object"
+	"coff-src/src/coff/parser"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const input = `def fib = fun(n) {
+	if (n < 2) { ret n; }
+	ret fib(n - 1) + fib(n - 2);
+};
+def greet = fun(name) { ret "hi " + name; };
+fib(3);
+greet("bob");
+fun() { ret [1, 2]; }();
+`
+
+// run profiles input with a clock that advances a millisecond each time
+// it is read.
+func run(t *testing.T) *Profiler {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	profiler := New("test.coff")
+	clock := time.Unix(0, 0)
+	profiler.now = func() time.Time {
+		clock = clock.Add(time.Millisecond)
+		return clock
+	}
+	profiler.Run(program, object.NewEnv())
+
+	return profiler
+}
+
+func TestFunctions(t *testing.T) {
+	profiler := run(t)
+
+	tests := []struct {
+		name                 string
+		line                 int
+		calls                int
+		inclusive, exclusive time.Duration
+		allocs               int
+	}{
+		{MAIN, 1, 1, 15 * time.Millisecond, 4 * time.Millisecond, 4},
+		{"fib", 1, 5, 9 * time.Millisecond, 9 * time.Millisecond, 0},
+		{"greet", 5, 1, time.Millisecond, time.Millisecond, 2},
+		{ANONYMOUS, 8, 1, time.Millisecond, time.Millisecond, 1},
+	}
+
+	functions := profiler.Functions()
+	if len(functions) != len(tests) {
+		t.Fatalf("expected %d functions, got=%d", len(tests), len(functions))
+	}
+	for i, tt := range tests {
+		fn := functions[i]
+		if fn.Name != tt.name || fn.Line != tt.line || fn.Calls != tt.calls ||
+			fn.Inclusive != tt.inclusive || fn.Exclusive != tt.exclusive || fn.Allocs != tt.allocs {
+			t.Errorf("functions[%d] wrong. expected=%+v, got=%+v", i, tt, *fn)
+		}
+	}
+}
+
+func TestModuleFunctions(t *testing.T) {
+	p := parser.New(lexer.New(`import "lib.coff" as lib; def f = fun() { lib.double(1) }; f();`))
+	profiler := New("testdata/main.coff")
+	env := object.NewEnv()
+	env.SetPath("testdata/main.coff")
+	profiler.Run(p.ParseProgram(), env)
+
+	files := map[string]string{}
+	for _, fn := range profiler.Functions() {
+		files[fn.Name] = fmt.Sprintf("%s:%d", fn.File, fn.Line)
+	}
+	expected := map[string]string{
+		MAIN:     "testdata/main.coff:1",
+		"f":      "testdata/main.coff:1",
+		"double": "testdata/lib.coff:1",
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("wrong files. expected=%v, got=%v", expected, files)
+	}
+}
+
+func TestReport(t *testing.T) {
+	var out bytes.Buffer
+	if err := run(t).WriteReport(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected a header and 4 functions, got=%q", out.String())
+	}
+	expected := "       5          9ms  60.00%          9ms  60.00%        0  fib test.coff:1"
+	if lines[2] != expected {
+		t.Errorf("wrong line for fib.\nexpected=%q\ngot=     %q", expected, lines[2])
+	}
+}
+
+func TestFolded(t *testing.T) {
+	var out bytes.Buffer
+	if err := run(t).WriteFolded(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `[main] 4000000
+[main];[anonymous] 1000000
+[main];fib 3000000
+[main];fib;fib 4000000
+[main];fib;fib;fib 2000000
+[main];greet 1000000
+`
+	if out.String() != expected {
+		t.Errorf("wrong folded stacks.\nexpected=%q\ngot=     %q", expected, out.String())
+	}
+}
+
+func TestPprof(t *testing.T) {
+	var out bytes.Buffer
+	if err := run(t).WritePprof(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	gz, err := gzip.NewReader(&out)
+	if err != nil {
+		t.Fatalf("profile is not gzipped: %s", err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each string table entry is field 6 with wire type 2, then its
+	// length.
+	for _, s := range []string{"calls", "time", "nanoseconds", "allocations", MAIN, "fib", "greet", "test.coff"} {
+		entry := append([]byte{6<<3 | 2, byte(len(s))}, s...)
+		if !bytes.Contains(data, entry) {
+			t.Errorf("string table has no %q", s)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package tester
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Failed returns the number of failed results.
+func Failed(results []Result) int {
+	n := 0
+	for _, r := range results {
+		if !r.Passed {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteText writes the failures and a line per file. If verbose, passing
+// tests are listed too.
+func WriteText(w io.Writer, files []string, results []Result, verbose bool) error {
+	for _, file := range files {
+		tests, failed := 0, 0
+		for _, r := range results {
+			if r.File != file {
+				continue
+			}
+			if r.Test != "" {
+				tests++
+			}
+
+			name := r.Test
+			if name == "" {
+				name = r.File
+			}
+			switch {
+			case !r.Passed:
+				failed++
+				fmt.Fprintf(w, "--- FAIL: %s\n    %s:%d:%d: %s\n", name, r.File, r.Line, r.Column,
+					strings.ReplaceAll(r.Message, "\n", "\n    "))
+			case verbose:
+				fmt.Fprintf(w, "--- PASS: %s\n", name)
+			}
+		}
+
+		var err error
+		if failed == 0 {
+			_, err = fmt.Fprintf(w, "ok   %s (%d tests)\n", file, tests)
+		} else {
+			_, err = fmt.Fprintf(w, "FAIL %s (%d of %d tests failed)\n", file, failed, tests)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the results as a JSON object.
+func WriteJSON(w io.Writer, results []Result) error {
+	if results == nil {
+		results = []Result{}
+	}
+
+	out := struct {
+		Passed  bool     `json:"passed"`
+		Results []Result `json:"results"`
+	}{Failed(results) == 0, results}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// WriteTAP writes the results in the Test Anything Protocol, version 13.
+func WriteTAP(w io.Writer, results []Result) error {
+	fmt.Fprintf(w, "TAP version 13\n1..%d\n", len(results))
+
+	for i, r := range results {
+		name := r.File
+		if r.Test != "" {
+			name += ": " + r.Test
+		}
+
+		if r.Passed {
+			fmt.Fprintf(w, "ok %d - %s\n", i+1, name)
+			continue
+		}
+
+		message, _ := json.Marshal(r.Message)
+		_, err := fmt.Fprintf(w, "not ok %d - %s\n  ---\n  message: %s\n  at: %s:%d:%d\n  ...\n",
+			i+1, name, message, r.File, r.Line, r.Column)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
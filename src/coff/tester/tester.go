@@ -0,0 +1,203 @@
+// Package tester runs CoffLang tests. A test file's name ends in
+// _test.coff, and each function it defines at the top level with a name
+// starting with test_ is a test. A test fails if it returns an error, as
+// the assertion builtins do when they fail.
+package tester
+
+import (
+	"coff-src/src/coff/ast"
+	"coff-src/src/coff/cover"
+	"coff-src/src/coff/eval"
+	"coff-src/src/coff/lexer"
+	"coff-src/src/coff/object"
+	"coff-src/src/coff/parser"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	FILE_SUFFIX = "_test.coff"
+	TEST_PREFIX = "test_"
+)
+
+// Result is the outcome of a test, or of a test file that failed before
+// its tests could run. Line and Column locate the failure, or the test's
+// definition if it passed.
+type Result struct {
+	File    string `json:"file"`
+	Test    string `json:"test,omitempty"` // empty for a failure of the whole file
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+// Files returns the test files among paths and under the directories in
+// paths, sorted.
+func Files(paths []string) ([]string, error) {
+	files := []string{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.HasSuffix(path, FILE_SUFFIX) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+type Runner struct {
+	// Coverage, if not nil, has the files run added to it. Its hooks
+	// must be installed for it to record anything.
+	Coverage *cover.Coverage
+}
+
+// RunFile runs the tests in the file at path.
+func (r *Runner) RunFile(path string) []Result {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return []Result{{File: path, Message: err.Error()}}
+	}
+	return r.Run(path, string(src))
+}
+
+// Run runs the tests in src, the contents of the file at path. Each test
+// runs in a fresh environment holding the file's top-level definitions.
+func (r *Runner) Run(path string, src string) []Result {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if errs := p.ParseErrors(); len(errs) != 0 {
+		return []Result{{
+			File:    path,
+			Line:    errs[0].Token.Line,
+			Column:  errs[0].Token.Column,
+			Message: strings.Join(p.Errors(), "\n"),
+		}}
+	}
+
+	if r.Coverage != nil {
+		r.Coverage.Add(path, src, program)
+	}
+
+	// statements holds the statement being evaluated in each active
+	// call, innermost last. As an error returns from a call, failedAt
+	// keeps the statement it came from.
+	var statements []ast.Node
+	var failedAt ast.Node
+	remove := eval.AddHooks(&eval.Hooks{
+		Node: func(node ast.Node, env *object.Env) {
+			if _, ok := node.(*ast.BlockStatement); ok {
+				return
+			}
+			if _, ok := node.(ast.Statement); ok {
+				statements[len(statements)-1] = node
+				failedAt = nil
+			}
+		},
+		Call: func(fn *object.Function, args []object.Object, env *object.Env) {
+			statements = append(statements, nil)
+		},
+		Return: func(fn *object.Function, result object.Object) {
+			if isError(result) && failedAt == nil {
+				failedAt = statements[len(statements)-1]
+			}
+			statements = statements[:len(statements)-1]
+		},
+	})
+	defer remove()
+
+	reset := func() {
+		statements, failedAt = []ast.Node{nil}, nil
+	}
+	failure := func(test string, err object.Object) Result {
+		at := failedAt
+		if at == nil {
+			at = statements[len(statements)-1]
+		}
+
+		result := Result{File: path, Test: test, Message: err.(*object.Error).Message}
+		if at != nil {
+			pos := ast.Start(at)
+			result.Line, result.Column = pos.Line, pos.Column
+		}
+		return result
+	}
+
+	tests := testsIn(program)
+	if len(tests) == 0 {
+		reset()
+		env := object.NewEnv()
+		env.SetPath(path)
+		if result := eval.Eval(program, env); isError(result) {
+			return []Result{failure("", result)}
+		}
+		return nil
+	}
+
+	results := []Result{}
+	for _, test := range tests {
+		env := object.NewEnv()
+		env.SetPath(path)
+		reset()
+		if result := eval.Eval(program, env); isError(result) {
+			return []Result{failure("", result)}
+		}
+
+		pos := ast.Start(test)
+		fn, _ := env.Get(test.Name.Value)
+		if fn, ok := fn.(*object.Function); !ok || len(fn.Parameters) != 0 {
+			results = append(results, Result{File: path, Test: test.Name.Value, Line: pos.Line, Column: pos.Column,
+				Message: "test functions must take no arguments"})
+			continue
+		}
+
+		reset()
+		if result := eval.Apply(fn, nil); isError(result) {
+			results = append(results, failure(test.Name.Value, result))
+			continue
+		}
+		results = append(results, Result{File: path, Test: test.Name.Value, Line: pos.Line, Column: pos.Column, Passed: true})
+	}
+
+	return results
+}
+
+// testsIn returns the definitions of tests in program, in source order.
+func testsIn(program *ast.Program) []*ast.DefStatement {
+	tests := []*ast.DefStatement{}
+	for _, stmt := range program.Statements {
+		def, ok := stmt.(*ast.DefStatement)
+		if !ok || def.Name == nil || !strings.HasPrefix(def.Name.Value, TEST_PREFIX) {
+			continue
+		}
+		if _, ok := def.Value.(*ast.FunctionLiteral); ok {
+			tests = append(tests, def)
+		}
+	}
+	return tests
+}
+
+func isError(obj object.Object) bool {
+	return obj != nil && obj.Type() == object.ERR_OBJ
+}
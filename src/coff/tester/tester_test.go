@@ -0,0 +1,143 @@
+package tester
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const input = `def add = fun(a, b) { a + b };
+
+def test_add = fun() {
+	assertEq(add(1, 2), 3);
+};
+
+def test_broken = fun() {
+	def sum = add(1, 1);
+	assertEq(sum, 3, "wrong sum");
+};
+
+def test_deep = fun() {
+	def check = fun(x) {
+		assert(x > 1, "too small");
+	};
+	check(2);
+	check(1);
+};
+
+def test_args = fun(x) { x };
+`
+
+func TestRun(t *testing.T) {
+	r := &Runner{}
+	results := r.Run("math_test.coff", input)
+
+	expected := []Result{
+		{File: "math_test.coff", Test: "test_add", Line: 3, Column: 1, Passed: true},
+		{File: "math_test.coff", Test: "test_broken", Line: 9, Column: 2, Message: "wrong sum"},
+		{File: "math_test.coff", Test: "test_deep", Line: 14, Column: 3, Message: "too small"},
+		{File: "math_test.coff", Test: "test_args", Line: 20, Column: 1, Message: "test functions must take no arguments"},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got=%+v", len(expected), results)
+	}
+	for i := range expected {
+		if results[i] != expected[i] {
+			t.Errorf("results[%d] wrong.\nexpected=%+v\ngot=     %+v", i, expected[i], results[i])
+		}
+	}
+}
+
+func TestFreshEnv(t *testing.T) {
+	input := `def counter = [];
+def test_first = fun() { assertEq(len(counter), 0); };
+def test_second = fun() { assertEq(len(counter), 0); };
+`
+	for _, r := range (&Runner{}).Run("env_test.coff", input) {
+		if !r.Passed {
+			t.Errorf("%s failed: %s", r.Test, r.Message)
+		}
+	}
+}
+
+func TestFileFailures(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Result
+	}{
+		{"def = 1;", Result{File: "f_test.coff", Line: 1, Column: 5, Message: "expected next token to be ID but got = instead\nno prefix parse function for = found"}},
+		{"def x = 1;\nx + true;\ndef test_x = fun() {};", Result{File: "f_test.coff", Line: 2, Column: 1, Message: "type mismatch: INT + BOOL"}},
+		{"fail(\"top level\");", Result{File: "f_test.coff", Line: 1, Column: 1, Message: "top level"}},
+	}
+
+	for _, tt := range tests {
+		results := (&Runner{}).Run("f_test.coff", tt.input)
+		if len(results) != 1 || results[0] != tt.expected {
+			t.Errorf("wrong results for %q.\nexpected=%+v\ngot=     %+v", tt.input, tt.expected, results)
+		}
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	var out bytes.Buffer
+	results := (&Runner{}).Run("math_test.coff", input)
+	WriteText(&out, []string{"math_test.coff"}, results, true)
+
+	expected := `--- PASS: test_add
+--- FAIL: test_broken
+    math_test.coff:9:2: wrong sum
+--- FAIL: test_deep
+    math_test.coff:14:3: too small
+--- FAIL: test_args
+    math_test.coff:20:1: test functions must take no arguments
+FAIL math_test.coff (3 of 4 tests failed)
+`
+	if out.String() != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=     %q", expected, out.String())
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var out bytes.Buffer
+	WriteJSON(&out, (&Runner{}).Run("math_test.coff", input))
+
+	decoded := struct {
+		Passed  bool
+		Results []Result
+	}{}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("output is not JSON: %s", err)
+	}
+	if decoded.Passed || len(decoded.Results) != 4 || decoded.Results[1].Message != "wrong sum" {
+		t.Errorf("wrong JSON: %s", out.String())
+	}
+}
+
+func TestWriteTAP(t *testing.T) {
+	var out bytes.Buffer
+	WriteTAP(&out, (&Runner{}).Run("math_test.coff", input)[:2])
+
+	expected := `TAP version 13
+1..2
+ok 1 - math_test.coff: test_add
+not ok 2 - math_test.coff: test_broken
+  ---
+  message: "wrong sum"
+  at: math_test.coff:9:2
+  ...
+`
+	if out.String() != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=     %q", expected, out.String())
+	}
+}
+
+func TestFiles(t *testing.T) {
+	files, err := Files([]string{"testdata"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(files, ",") != "testdata/list_test.coff,testdata/nested/math_test.coff" {
+		t.Errorf("wrong files: %v", files)
+	}
+}
@@ -5,11 +5,14 @@ type TokenType string
 type Token struct {
 	Type 	TokenType
 	Literal string
+	Line	int // 1-based line of the first character
+	Column	int // 1-based column of the first character
 }
 
 const (
 	INVALID 	= "INVALID"
 	EOF 		= "EOF"
+	COMMENT		= "COMMENT"
 
 	ID			= "ID"
 	INT			= "INT"
@@ -24,17 +27,35 @@ const (
 	FAC			= "!"
 
 	QUERY		= "?" // TODO: Add to switch
-	MOD 		= "%" // TODO: Add to switch
+	MOD 		= "%"
+	FLOOR_DIV	= "FLOOR_DIV" // div, division rounding down
+	FLOOR_MOD	= "FLOOR_MOD" // mod, the remainder of div
+	POW			= "**"
+
+	BIT_AND		= "&"
+	BIT_OR		= "|"
+	BIT_XOR		= "^"
+	BIT_NOT		= "~"
+	SHL			= "<<"
+	SHR			= ">>"
 
 	LT 			= "<"
 	GT 			= ">"
+	LT_EQ		= "<="
+	GT_EQ		= ">="
 
 	EQ			= "=="
 	NOT_EQ		= "!="
 
+	AND			= "&&"
+	OR			= "||"
+
 	COMMA 		= ","
 	SEMICOLON	= ";"
 	COLON 		= ":"
+	DOT			= "."
+	ELLIPSIS	= "..."
+	ARROW		= "=>"
 
 	LPAR 		= "("
 	RPAR 		= ")"
@@ -50,9 +71,20 @@ const (
 	IF			= "IF"
 	ELSE		= "ELSE"
 	RET			= "RET"
+	IMPORT		= "IMPORT"
+	AS			= "AS"
+	EXPORT		= "EXPORT"
+	STRUCT		= "STRUCT"
+	CLASS		= "CLASS"
+	MATCH		= "MATCH"
+	THROW		= "THROW"
+	TRY			= "TRY"
+	CATCH		= "CATCH"
+	FINALLY		= "FINALLY"
 	
-	IS			= "IS"	// TODO: Add to keywords
-	NOT 		= "NOT" // TODO: Add to keywords
+	IS			= "IS"
+	IN			= "IN"
+	NOT 		= "NOT"
 	NUL			= "NUL" // TODO: Add to keywords
 	NIL			= "NIL" // TODO: Add to keywords
 )
@@ -65,6 +97,23 @@ var keywords = map[string]TokenType {
 	"if": IF,
 	"else": ELSE,
 	"ret": RET,
+	"import": IMPORT,
+	"as": AS,
+	"export": EXPORT,
+	"struct": STRUCT,
+	"class": CLASS,
+	"match": MATCH,
+	"throw": THROW,
+	"try": TRY,
+	"catch": CATCH,
+	"finally": FINALLY,
+	"is": IS,
+	"in": IN,
+	"and": AND,
+	"or": OR,
+	"not": NOT,
+	"div": FLOOR_DIV,
+	"mod": FLOOR_MOD,
 }
 
 func LookupId(id string) TokenType {
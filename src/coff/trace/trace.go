@@ -0,0 +1,97 @@
+// Package trace logs the calls and returns of CoffLang functions as a
+// program runs, indented by call depth. Calls of builtins such as len or
+// print are not logged: the evaluator only reports calls of functions
+// defined in CoffLang to its hooks.
+package trace
+
+import (
+	"coff-src/src/coff/eval"
+	"coff-src/src/coff/object"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type Tracer struct {
+	// Out is where the trace is written.
+	Out io.Writer
+
+	// Functions, if not empty, limits the trace to calls of the functions
+	// with these names. Depth then counts only traced calls.
+	Functions []string
+
+	depth  int
+	traced []bool // whether each active call is traced, innermost last
+}
+
+func New(out io.Writer, functions ...string) *Tracer {
+	return &Tracer{Out: out, Functions: functions}
+}
+
+// Hooks returns the evaluation hooks that write the trace, to be
+// installed with eval.AddHooks.
+func (t *Tracer) Hooks() *eval.Hooks {
+	return &eval.Hooks{Call: t.call, Return: t.ret}
+}
+
+func (t *Tracer) call(fn *object.Function, args []object.Object, env *object.Env) {
+	if !t.traces(fn) {
+		t.traced = append(t.traced, false)
+		return
+	}
+	t.traced = append(t.traced, true)
+
+	inspected := []string{}
+	for _, arg := range args {
+		inspected = append(inspected, inspect(arg))
+	}
+	fmt.Fprintf(t.Out, "%scall %s(%s)\n", t.indent(), name(fn), strings.Join(inspected, ", "))
+	t.depth++
+}
+
+func (t *Tracer) ret(fn *object.Function, result object.Object) {
+	traced := t.traced[len(t.traced)-1]
+	t.traced = t.traced[:len(t.traced)-1]
+	if !traced {
+		return
+	}
+
+	t.depth--
+	fmt.Fprintf(t.Out, "%sreturn %s = %s\n", t.indent(), name(fn), inspect(result))
+}
+
+func (t *Tracer) traces(fn *object.Function) bool {
+	if len(t.Functions) == 0 {
+		return true
+	}
+	for _, f := range t.Functions {
+		if f == fn.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Tracer) indent() string {
+	return strings.Repeat("  ", t.depth)
+}
+
+func name(fn *object.Function) string {
+	if fn.Name == "" {
+		return "<anonymous>"
+	}
+	return fn.Name
+}
+
+// inspect shortens multi-line values such as functions to their first
+// line.
+func inspect(obj object.Object) string {
+	if obj == nil {
+		return "null"
+	}
+	s := obj.Inspect()
+	if i := strings.Index(s, "\n"); i >= 0 {
+		return s[:i] + " ... }"
+	}
+	return s
+}
@@ -0,0 +1,66 @@
+package trace
+
+import (
+	"bytes"
+	"coff-src/src/coff/eval"
+	"coff-src/src/coff/lexer"
+	"coff-src/src/coff/object"
+	"coff-src/src/coff/parser"
+	"testing"
+)
+
+const input = `def fib = fun(n) {
+	if (n < 2) { ret n; }
+	ret fib(n - 1) + fib(n - 2);
+};
+def apply = fun(f, x) { f(x) };
+apply(fib, 2);
+apply(fun(s) { s + "!" }, "hi");
+`
+
+func run(t *testing.T, functions ...string) string {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	var out bytes.Buffer
+	remove := eval.AddHooks(New(&out, functions...).Hooks())
+	defer remove()
+	eval.Eval(program, object.NewEnv())
+
+	return out.String()
+}
+
+func TestTrace(t *testing.T) {
+	expected := `call apply(fn(n) { ... }, 2)
+  call fib(2)
+    call fib(1)
+    return fib = 1
+    call fib(0)
+    return fib = 0
+  return fib = 1
+return apply = 1
+call apply(fn(s) { ... }, hi)
+  call <anonymous>(hi)
+  return <anonymous> = hi!
+return apply = hi!
+`
+	if got := run(t); got != expected {
+		t.Errorf("wrong trace.\nexpected=%q\ngot=     %q", expected, got)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	expected := `call fib(2)
+  call fib(1)
+  return fib = 1
+  call fib(0)
+  return fib = 0
+return fib = 1
+`
+	if got := run(t, "fib"); got != expected {
+		t.Errorf("wrong trace.\nexpected=%q\ngot=     %q", expected, got)
+	}
+}